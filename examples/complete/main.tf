@@ -36,6 +36,7 @@ module "sonarqube" {
     john_doe = {
       name  = "John Doe"
       email = "john.doe@example.com"
+      local = false
       scm_accounts = ["github/johndoe"]
     }
   }
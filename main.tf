@@ -41,6 +41,7 @@ resource "sonarqube_user" "user" {
   name        = each.value.name
   email       = each.value.email
   password    = each.value.password
+  local       = each.value.local
   scm_accounts = each.value.scm_accounts
 }
 
@@ -0,0 +1,217 @@
+// Package audit writes and queries the JSON-lines log of changes the
+// provider makes in SonarQube
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record describes one mutating API request. Terraform does not tell
+// providers the address of the resource being applied, so the resource is
+// identified by type and ID instead.
+type Record struct {
+	Time         time.Time           `json:"time"`
+	ResourceType string              `json:"resource_type,omitempty"`
+	ResourceID   string              `json:"resource_id,omitempty"`
+	Operation    string              `json:"operation,omitempty"`
+	Method       string              `json:"method"`
+	Endpoint     string              `json:"endpoint"`
+	Params       map[string][]string `json:"params,omitempty"`
+	Status       int                 `json:"status"`
+	DurationMS   int64               `json:"duration_ms"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// Log appends records to a file, one JSON object per line. Each record is
+// written with a single append, so the separate plan and apply provider
+// processes can share a file.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens path for appending, creating it if needed
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: f}, nil
+}
+
+// Write appends r to the log
+func (l *Log) Write(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+type resourceKey struct{}
+
+type resource struct {
+	resourceType, id, operation string
+
+	// held is set while the ID is not known yet
+	held *heldRecords
+}
+
+// heldRecords are the records of a resource being created, waiting for the
+// ID the create settles on
+type heldRecords struct {
+	mu      sync.Mutex
+	done    bool
+	id      string
+	records []heldRecord
+}
+
+type heldRecord struct {
+	log    *Log
+	record Record
+}
+
+// WithResource attaches the Terraform resource and operation on whose
+// behalf requests made with ctx are sent. Call the returned function with
+// the resource's ID once the operation returns. While id is empty, as it is
+// during a create, records appended with ctx are held until then, so they
+// carry the ID the resource was created with.
+func WithResource(ctx context.Context, resourceType, id, operation string) (context.Context, func(id string) error) {
+	res := resource{resourceType: resourceType, id: id, operation: operation}
+	if id != "" {
+		return context.WithValue(ctx, resourceKey{}, res), func(string) error { return nil }
+	}
+
+	res.held = &heldRecords{}
+	return context.WithValue(ctx, resourceKey{}, res), res.held.release
+}
+
+// release writes the held records under id. Records appended after it are
+// written straight away, under id too.
+func (h *heldRecords) release(id string) error {
+	h.mu.Lock()
+	records := h.records
+	h.records, h.done, h.id = nil, true, id
+	h.mu.Unlock()
+
+	var errs []error
+	for _, held := range records {
+		held.record.ResourceID = id
+		if err := held.log.Write(held.record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResourceFromContext returns the resource attached with WithResource, or
+// empty strings
+func ResourceFromContext(ctx context.Context) (resourceType, id, operation string) {
+	res, _ := ctx.Value(resourceKey{}).(resource)
+	return res.resourceType, res.id, res.operation
+}
+
+// Append fills in the resource fields of r from ctx and writes it, or holds
+// it while the resource's ID is not known yet
+func (l *Log) Append(ctx context.Context, r Record) error {
+	res, _ := ctx.Value(resourceKey{}).(resource)
+	r.ResourceType, r.ResourceID, r.Operation = res.resourceType, res.id, res.operation
+
+	if res.held != nil {
+		res.held.mu.Lock()
+		if !res.held.done {
+			res.held.records = append(res.held.records, heldRecord{log: l, record: r})
+			res.held.mu.Unlock()
+			return nil
+		}
+		r.ResourceID = res.held.id
+		res.held.mu.Unlock()
+	}
+	return l.Write(r)
+}
+
+// Query selects records. Zero fields match everything.
+type Query struct {
+	ResourceType string
+	ResourceID   string
+
+	// Since and Until bound Record.Time, inclusive of Since and exclusive
+	// of Until
+	Since time.Time
+	Until time.Time
+}
+
+// Matches reports whether r is selected by q
+func (q Query) Matches(r Record) bool {
+	switch {
+	case q.ResourceType != "" && r.ResourceType != q.ResourceType:
+		return false
+	case q.ResourceID != "" && r.ResourceID != q.ResourceID:
+		return false
+	case !q.Since.IsZero() && r.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !r.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Decode reads the records matching q from a log, in the order they were
+// written
+func Decode(r io.Reader, q Query) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid audit record on line %d: %w", line, err)
+		}
+		if q.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// Read returns the records matching q from the log at path. A missing file
+// holds no records.
+func Read(path string, q Query) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	return Decode(f, q)
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	log, err := Open(path)
+	require.NoError(t, err)
+	for i, r := range []Record{
+		{ResourceType: "sonarqube_project", ResourceID: "a", Endpoint: "projects/create"},
+		{ResourceType: "sonarqube_project", ResourceID: "b", Endpoint: "projects/create"},
+		{ResourceType: "sonarqube_qualitygate", ResourceID: "Gate", Endpoint: "qualitygates/create"},
+		{ResourceType: "sonarqube_project", ResourceID: "a", Endpoint: "projects/delete"},
+	} {
+		r.Time = start.Add(time.Duration(i) * time.Hour)
+		r.Method = "POST"
+		require.NoError(t, log.Write(r))
+	}
+	require.NoError(t, log.Close())
+
+	all, err := Read(path, Query{})
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+
+	projectA, err := Read(path, Query{ResourceType: "sonarqube_project", ResourceID: "a"})
+	require.NoError(t, err)
+	require.Len(t, projectA, 2)
+	assert.Equal(t, "projects/delete", projectA[1].Endpoint)
+
+	window, err := Read(path, Query{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, window, 2)
+	assert.Equal(t, "b", window[0].ResourceID)
+	assert.Equal(t, "Gate", window[1].ResourceID)
+}
+
+func TestReadMissingLog(t *testing.T) {
+	records, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"), Query{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestAppendHoldsRecordsUntilIDKnown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	require.NoError(t, err)
+	defer log.Close()
+
+	ctx, done := WithResource(context.Background(), "sonarqube_project", "", "create")
+	require.NoError(t, log.Append(ctx, Record{Endpoint: "projects/create"}))
+	require.NoError(t, log.Append(ctx, Record{Endpoint: "project_tags/set"}))
+
+	held, err := Read(path, Query{})
+	require.NoError(t, err)
+	assert.Empty(t, held, "nothing is written before the ID is known")
+
+	require.NoError(t, done("demo"))
+	require.NoError(t, log.Append(ctx, Record{Endpoint: "projects/update_visibility"}))
+
+	records, err := Read(path, Query{ResourceType: "sonarqube_project", ResourceID: "demo"})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "projects/create", records[0].Endpoint)
+	assert.Equal(t, "create", records[0].Operation)
+	assert.Equal(t, "project_tags/set", records[1].Endpoint)
+	assert.Equal(t, "projects/update_visibility", records[2].Endpoint, "records after the create are filed under its ID too")
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"strings"
+	"time"
+)
+
+var authModes = []string{
+	string(client.AuthAuto),
+	string(client.AuthBearer),
+	string(client.AuthBasicToken),
+	string(client.AuthPassword),
+}
+
+// credentialSources are the attributes that each supply the credentials on
+// their own
+var credentialSources = []string{"token", "token_file", "token_command", "username"}
+
+// configureAuth returns the client options for the credentials in the
+// provider block. Exactly one credential source must be set, and username
+// and password must be set together; this is checked here rather than with
+// ExactlyOneOf and RequiredWith so environment variables count.
+func configureAuth(d *schema.ResourceData) ([]client.ClientOption, diag.Diagnostics) {
+	if (d.Get("username").(string) == "") != (d.Get("password").(string) == "") {
+		return nil, diag.Errorf("username and password must be set together")
+	}
+
+	var set []string
+	for _, attr := range credentialSources {
+		if d.Get(attr).(string) != "" {
+			set = append(set, attr)
+		}
+	}
+	if len(set) != 1 {
+		return nil, diag.Errorf("exactly one of %s must be set, got %d", strings.Join(credentialSources, ", "), len(set))
+	}
+
+	mode := client.AuthMode(d.Get("auth_mode").(string))
+	if set[0] == "username" {
+		if mode != client.AuthAuto && mode != client.AuthPassword {
+			return nil, diag.Errorf("auth_mode %q cannot be used with username, which logs in with a password", mode)
+		}
+		return []client.ClientOption{client.WithBasicAuth(d.Get("username").(string), d.Get("password").(string))}, nil
+	}
+	if mode == client.AuthPassword {
+		return nil, diag.Errorf("auth_mode %q requires username and password", mode)
+	}
+
+	opts := []client.ClientOption{client.WithAuthMode(mode)}
+	switch set[0] {
+	case "token_file":
+		opts = append(opts, client.WithTokenSource(&client.FileTokenSource{Path: d.Get("token_file").(string)}))
+	case "token_command":
+		var ttl time.Duration
+		if v := d.Get("token_command_ttl").(string); v != "" {
+			ttl, _ = time.ParseDuration(v)
+		}
+		opts = append(opts, client.WithTokenSource(&client.CommandTokenSource{Command: d.Get("token_command").(string), TTL: ttl}))
+	}
+	return opts, nil
+}
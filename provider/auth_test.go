@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProviderConfigureAuth(t *testing.T) {
+	fake := newFakeSonar(t, fakesonar.WithAdminPassword("s3cret"))
+	ctx := context.Background()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(fakesonar.DefaultToken+"\n"), 0o600))
+
+	for name, config := range map[string]map[string]interface{}{
+		"token_file":    {"token_file": tokenFile},
+		"token_command": {"token_command": "echo " + fakesonar.DefaultToken, "token_command_ttl": "15m"},
+		"basic-token":   {"token": fakesonar.DefaultToken, "auth_mode": "basic-token"},
+		"password":      {"username": "admin", "password": "s3cret"},
+	} {
+		config["host"] = fake.URL
+		p := Provider()
+		diags := p.Configure(ctx, terraform.NewResourceConfigRaw(config))
+		require.False(t, diags.HasError(), "%s: %v", name, diags)
+
+		_, err := p.Meta().(client.Projects).CreateProject(ctx, name, name, "public", "", nil)
+		assert.NoError(t, err, name)
+	}
+
+	for name, config := range map[string]map[string]interface{}{
+		"no credentials":         {},
+		"two credentials":        {"token": fakesonar.DefaultToken, "token_file": tokenFile},
+		"password without login": {"token": fakesonar.DefaultToken, "auth_mode": "password"},
+		"username only":          {"username": "admin"},
+		"password only":          {"token": fakesonar.DefaultToken, "password": "s3cret"},
+	} {
+		config["host"] = fake.URL
+		diags := Provider().Configure(ctx, terraform.NewResourceConfigRaw(config))
+		assert.True(t, diags.HasError(), name)
+	}
+}
+
+func TestProviderConfigureOAuth2(t *testing.T) {
+	fake := newFakeSonar(t)
+	var forms []string
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		forms = append(forms, r.PostForm.Encode())
+		_, _ = w.Write([]byte(`{"access_token":"proxy-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer idp.Close()
+
+	diags := Provider().Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  fake.URL,
+		"token": fakesonar.DefaultToken,
+		"oauth2": []interface{}{map[string]interface{}{
+			"token_url":     idp.URL,
+			"client_id":     "terraform",
+			"client_secret": "s3cret",
+			"scopes":        []interface{}{"sonarqube"},
+		}},
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+	assert.Equal(t, []string{"grant_type=client_credentials&scope=sonarqube"}, forms, "server detection fetched one token")
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLogRecordsMutations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/projects/search" {
+			_, _ = w.Write([]byte(`{"paging":{"total":1},"components":[{"key":"demo"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(path)
+	require.NoError(t, err)
+	defer log.Close()
+
+	c := NewClient(srv.URL, "token", WithAuditLog(log))
+	ctx, _ := audit.WithResource(context.Background(), "sonarqube_project", "demo", "update")
+
+	_, err = c.ReadProject(ctx, "demo")
+	require.NoError(t, err)
+	require.NoError(t, c.SetSetting(ctx, "", "sonar.auth.github.clientSecret.secured", "s3cret"))
+	require.NoError(t, c.call(ctx, newRequest(http.MethodPost, "users/change_password").
+		Set("login", "bob").
+		Set("password", "hunter2")))
+
+	records, err := audit.Read(path, audit.Query{ResourceType: "sonarqube_project", ResourceID: "demo"})
+	require.NoError(t, err)
+	require.Len(t, records, 2, "GETs are not audited")
+
+	assert.Equal(t, "settings/set", records[0].Endpoint)
+	assert.Equal(t, "update", records[0].Operation)
+	assert.Equal(t, http.StatusNoContent, records[0].Status)
+	assert.Equal(t, []string{redacted}, records[0].Params["value"])
+	assert.Equal(t, []string{"bob"}, records[1].Params["login"])
+	assert.Equal(t, []string{redacted}, records[1].Params["password"])
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthMode selects how credentials are sent to SonarQube
+type AuthMode string
+
+const (
+	// AuthAuto sends tokens as bearer tokens to SonarQube 10.0 and newer,
+	// and as the basic auth username to older servers. Until the server has
+	// been detected, bearer is assumed, unless detection itself was refused
+	// a bearer token.
+	AuthAuto AuthMode = "auto"
+
+	// AuthBearer sends "Authorization: Bearer <token>"
+	AuthBearer AuthMode = "bearer"
+
+	// AuthBasicToken sends the token as the basic auth username with an
+	// empty password
+	AuthBasicToken AuthMode = "basic-token"
+
+	// AuthPassword sends a username and password with basic auth
+	AuthPassword AuthMode = "password"
+)
+
+// TokenSource supplies the token sent with each request
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// WithAuthMode changes how credentials are sent. The default is AuthAuto.
+func WithAuthMode(mode AuthMode) ClientOption {
+	return func(c *Client) {
+		c.authMode = mode
+	}
+}
+
+// WithTokenSource takes the token from src instead of the token passed to
+// NewClient
+func WithTokenSource(src TokenSource) ClientOption {
+	return func(c *Client) {
+		c.tokens = src
+	}
+}
+
+// WithBasicAuth logs in with a username and password instead of a token
+func WithBasicAuth(username, password string) ClientOption {
+	return func(c *Client) {
+		c.authMode = AuthPassword
+		c.username = username
+		c.password = password
+	}
+}
+
+// setAuthorization adds the credentials to h
+func (c *Client) setAuthorization(ctx context.Context, h http.Header) error {
+	mode := c.authMode
+	if mode == AuthPassword {
+		h.Set("Authorization", basicAuth(c.username, c.password))
+		return nil
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get SonarQube token: %w", err)
+	}
+
+	if mode == AuthAuto || mode == "" {
+		mode = AuthBearer
+		if c.server != nil && !c.server.Version.AtLeast(10, 0) || c.server == nil && c.authFallback {
+			mode = AuthBasicToken
+		}
+	}
+
+	if mode == AuthBasicToken {
+		h.Set("Authorization", basicAuth(token, ""))
+		return nil
+	}
+	h.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// FileTokenSource reads the token from a file the first time it is needed,
+// trimming surrounding whitespace
+type FileTokenSource struct {
+	Path string
+
+	once  sync.Once
+	token string
+	err   error
+}
+
+func (s *FileTokenSource) Token(context.Context) (string, error) {
+	s.once.Do(func() {
+		data, err := os.ReadFile(s.Path)
+		if err != nil {
+			s.err = fmt.Errorf("failed to read token file: %w", err)
+			return
+		}
+		s.token = strings.TrimSpace(string(data))
+		if s.token == "" {
+			s.err = fmt.Errorf("token file %s is empty", s.Path)
+		}
+	})
+	return s.token, s.err
+}
+
+// CommandTokenSource runs a shell command and uses its trimmed standard
+// output as the token, e.g. to fetch it from a secret manager. The token is
+// cached for TTL, or for the life of the process when TTL is zero.
+// Concurrent requests share one run of the command.
+type CommandTokenSource struct {
+	Command string
+	TTL     time.Duration
+
+	mu      sync.Mutex
+	token   string
+	fetched time.Time
+}
+
+func (s *CommandTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && (s.TTL == 0 || time.Since(s.fetched) < s.TTL) {
+		return s.token, nil
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, shell, flag, s.Command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("token command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.New("token command printed no token")
+	}
+	s.token, s.fetched = token, time.Now()
+	return token, nil
+}
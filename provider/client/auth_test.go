@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthTestServer reports version and records the Authorization header of
+// every other request
+func newAuthTestServer(t *testing.T, version string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/server/version":
+			_, _ = w.Write([]byte(version))
+		case "/api/navigation/global":
+			_, _ = w.Write([]byte(`{"edition":"community"}`))
+		default:
+			seen = append(seen, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
+
+func basicHeader(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestAuthAutoFollowsServerVersion(t *testing.T) {
+	ctx := context.Background()
+	for version, want := range map[string]string{
+		"9.9.1.69595":  basicHeader("token", ""),
+		"10.2.0.77647": "Bearer token",
+	} {
+		srv, seen := newAuthTestServer(t, version)
+		c := NewClient(srv.URL, "token")
+
+		require.NoError(t, c.DeleteProject(ctx, "demo"))
+		_, err := c.DetectServer(ctx)
+		require.NoError(t, err)
+		require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+		assert.Equal(t, []string{"Bearer token", want}, *seen, version)
+	}
+}
+
+func TestAuthExplicitModes(t *testing.T) {
+	ctx := context.Background()
+	srv, seen := newAuthTestServer(t, "10.2.0.77647")
+
+	c := NewClient(srv.URL, "token", WithAuthMode(AuthBasicToken))
+	_, err := c.DetectServer(ctx)
+	require.NoError(t, err)
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+	c = NewClient(srv.URL, "", WithBasicAuth("admin", "s3cret"))
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+	assert.Equal(t, []string{basicHeader("token", ""), basicHeader("admin", "s3cret")}, *seen)
+}
+
+func TestAuthPasswordAgainstFakeServer(t *testing.T) {
+	srv := fakesonar.NewServer(fakesonar.WithAdminPassword("s3cret"))
+	defer srv.Close()
+	ctx := context.Background()
+	noRetries := WithRetryConfig(RetryConfig{MaxRetries: 0})
+
+	_, err := NewClient(srv.URL, "", WithBasicAuth("admin", "s3cret"), noRetries).DetectServer(ctx)
+	assert.NoError(t, err)
+
+	err = NewClient(srv.URL, "", WithBasicAuth("admin", "wrong"), noRetries).DeleteProject(ctx, "demo")
+	assert.Equal(t, ErrorTypeAuth, ClassifyError(err))
+}
+
+func TestAuthAutoFallsBackWhenDetectionIsForced(t *testing.T) {
+	ctx := context.Background()
+	noRetries := WithRetryConfig(RetryConfig{MaxRetries: 0})
+
+	srv := fakesonar.NewServer(fakesonar.WithForceAuthentication())
+	defer srv.Close()
+	c := NewClient(srv.URL, fakesonar.DefaultToken, noRetries)
+	info, err := c.DetectServer(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fakesonar.DefaultVersion, info.Version.String())
+	_, err = c.CreateProject(ctx, "Demo", "demo", "public", "", nil)
+	require.NoError(t, err)
+
+	modern := fakesonar.NewServer(fakesonar.WithForceAuthentication(), fakesonar.WithVersion("10.2.0.77647"))
+	defer modern.Close()
+	c = NewClient(modern.URL, fakesonar.DefaultToken, noRetries)
+	_, err = c.DetectServer(ctx)
+	require.NoError(t, err)
+	assert.False(t, c.authFallback, "a 10.x server accepts the bearer token")
+
+	_, err = NewClient(srv.URL, "wrong", noRetries).DetectServer(ctx)
+	assert.True(t, IsUnauthorized(err), "got %v", err)
+}
+
+func TestFileTokenSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	src := &FileTokenSource{Path: path}
+	token, err := src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", token)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte(" \n"), 0o600))
+	_, err = (&FileTokenSource{Path: empty}).Token(context.Background())
+	assert.ErrorContains(t, err, "is empty")
+
+	_, err = (&FileTokenSource{Path: filepath.Join(dir, "missing")}).Token(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCommandTokenSourceCaches(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	ctx := context.Background()
+
+	src := &CommandTokenSource{Command: "echo run >> " + counter + " && echo from-command"}
+	for i := 0; i < 3; i++ {
+		token, err := src.Token(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "from-command", token)
+	}
+	runs, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(runs), "run"), "the token is cached without a TTL")
+
+	src = &CommandTokenSource{Command: "echo run >> " + counter + " && echo again", TTL: time.Nanosecond}
+	_, err = src.Token(ctx)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = src.Token(ctx)
+	require.NoError(t, err)
+	runs, err = os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(runs), "run"), "an expired token is fetched again")
+
+	_, err = (&CommandTokenSource{Command: "echo denied >&2; exit 3"}).Token(ctx)
+	assert.ErrorContains(t, err, "denied")
+	_, err = (&CommandTokenSource{Command: "true"}).Token(ctx)
+	assert.ErrorContains(t, err, "printed no token")
+}
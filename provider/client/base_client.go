@@ -1,13 +1,19 @@
+// Package client provides a SonarQube API client
 package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/sirupsen/logrus"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"github.com/tomer1983/terraform-provider-sonarqube/metrics"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"io"
 	"net/http"
 	"time"
 )
@@ -22,12 +28,30 @@ type ClientOption func(*Client)
 
 type Client struct {
 	host           string
-	token          string
+	tokens         TokenSource
+	authMode       AuthMode
+	authFallback   bool
+	username       string
+	password       string
+	oauth2         *oauth2Source
 	client         *retryablehttp.Client
 	logger         *logrus.Logger
 	tracer         trace.Tracer
 	metricsEnabled bool
 	retryConfig    RetryConfig
+	rateLimit      RateLimitConfig
+	server         *ServerInfo
+	cassette       *cassetteConfig
+	callTimeout    time.Duration
+	requestTimeout time.Duration
+	tlsConfig      *tls.Config
+	proxyURL       string
+	noProxy        string
+	headers        map[string]string
+	audit          *audit.Log
+	dryRun         *DryRun
+	taskPollMin    time.Duration
+	taskPollMax    time.Duration
 }
 
 type RetryConfig struct {
@@ -40,9 +64,10 @@ type RetryConfig struct {
 // NewClient creates a new SonarQube client with options
 func NewClient(host, token string, opts ...ClientOption) *Client {
 	c := &Client{
-		host:   host,
-		token:  token,
-		logger: logrus.New(),
+		host:     host,
+		tokens:   StaticToken(token),
+		authMode: AuthAuto,
+		logger:   logrus.New(),
 		tracer: otel.Tracer("sonarqube-client"),
 		retryConfig: RetryConfig{
 			MaxRetries: defaultRetryMax,
@@ -80,22 +105,96 @@ func WithTelemetry() ClientOption {
 	}
 }
 
+// WithTracerProvider enables tracing like WithTelemetry, but creates spans
+// with tp instead of the global tracer provider
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracer = tp.Tracer("sonarqube-client")
+		c.metricsEnabled = true
+	}
+}
+
+// WithCallTimeout bounds each API call, including its retries and backoff
+// waits, by d. Deadlines already on the caller's context still apply.
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.callTimeout = d
+	}
+}
+
+// WithRequestTimeout bounds each HTTP attempt by d, from sending the request
+// to reading the response body. A timed out attempt is retried like any
+// other network failure, unlike WithCallTimeout, which ends the call.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.requestTimeout = d
+	}
+}
+
+// WithAuditLog appends a record of every mutating request to log. Secret
+// parameters are redacted.
+func WithAuditLog(log *audit.Log) ClientOption {
+	return func(c *Client) {
+		c.audit = log
+	}
+}
+
 func (c *Client) setupHTTPClient() {
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = c.retryConfig.MaxRetries
 	retryClient.RetryWaitMin = c.retryConfig.WaitMin
 	retryClient.RetryWaitMax = c.retryConfig.WaitMax
 	
+	retryClient.CheckRetry = c.retryPolicy
+	retryClient.Backoff = retryBackoff
+	// Hand the last response back to doRequest so it becomes an APIError
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
 	if c.retryConfig.RetryPolicy != nil {
 		retryClient.CheckRetry = c.retryConfig.RetryPolicy
 	}
 
 	retryClient.Logger = nil // Disable default logger
+	retryClient.HTTPClient.Timeout = c.requestTimeout
+
+	transport := retryClient.HTTPClient.Transport
+	c.applyTLSConfig(transport)
+	c.applyProxy(transport)
+	if c.cassette != nil {
+		transport = newCassetteTransport(c.cassette, transport)
+	}
+	if c.oauth2 != nil {
+		// The token endpoint is reached with the same TLS and proxy settings
+		c.oauth2.client = &http.Client{Transport: retryClient.HTTPClient.Transport, Timeout: c.requestTimeout}
+		transport = &oauth2Transport{base: transport, source: c.oauth2}
+	}
+
+	retryClient.HTTPClient.Transport = &limitedTransport{
+		base:    transport,
+		limiter: newAdaptiveLimiter(c.rateLimit, c.logger),
+	}
 
 	c.client = retryClient
 }
 
-func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+func (c *Client) doRequest(ctx context.Context, r *Request) (*http.Response, error) {
+	method, path := r.Method, r.Path
+
+	if c.dryRun != nil {
+		if resp, handled, err := c.dryRun.intercept(ctx, c, r); handled {
+			return resp, err
+		}
+	}
+
+	// The deadline has to outlive doRequest while the caller reads the body,
+	// so it is released when the body is closed
+	cancel := context.CancelFunc(func() {})
+	if c.callTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+	}
+	release := cancel
+	defer func() { release() }()
+
 	var span trace.Span
 	if c.metricsEnabled {
 		ctx, span = c.tracer.Start(ctx, "SonarQube."+method+"."+path,
@@ -106,48 +205,109 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 		defer span.End()
 	}
 
-	url := fmt.Sprintf("%s/api/%s", c.host, path)
-	
-	req, err := retryablehttp.NewRequest(method, url, body)
+	req, err := r.build(withMethod(ctx, method), c.host)
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to create request")
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
-	req.Header.Set("Content-Type", "application/json")
+	c.setExtraHeaders(req.Header)
+	if err := c.setAuthorization(ctx, req.Header); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
 
 	start := time.Now()
 	resp, err := c.client.Do(req)
 	duration := time.Since(start)
 
+	metricPath := metrics.NormalizePath(path)
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	metrics.RecordAPIRequest(method, metricPath, statusCode, duration)
+
 	if c.metricsEnabled {
-		span.SetAttributes(
-			attribute.Int64("http.duration_ms", duration.Milliseconds()),
-			attribute.Int("http.status_code", resp.StatusCode),
-		)
+		span.SetAttributes(attribute.Int64("http.duration_ms", duration.Milliseconds()))
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		}
+	}
+
+	if c.audit != nil && method != http.MethodGet {
+		c.recordMutation(ctx, r, start, statusCode, duration, err)
 	}
 
 	c.logger.WithFields(logrus.Fields{
 		"method":   method,
 		"path":     path,
 		"duration": duration,
-		"status":   resp.StatusCode,
+		"status":   statusCode,
 	}).Debug("API request completed")
 
 	if err != nil {
 		c.logger.WithError(err).Error("Request failed")
+		metrics.RecordAPIError(method, metricPath, ClassifyError(err))
+		if c.metricsEnabled {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "request failed")
+		}
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		c.logger.WithField("status", resp.StatusCode).Error("API request failed")
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		apiErr := newAPIError(method, path, resp.StatusCode, respBody)
+		c.logger.WithFields(logrus.Fields{
+			"status":   resp.StatusCode,
+			"messages": apiErr.Messages,
+		}).Error("API request failed")
+		metrics.RecordAPIError(method, metricPath, ClassifyError(apiErr))
+		if c.metricsEnabled {
+			span.RecordError(apiErr)
+			span.SetStatus(codes.Error, ClassifyError(apiErr))
+		}
+		return nil, apiErr
 	}
 
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	release = func() {}
 	return resp, nil
 }
 
+// recordMutation appends r and its outcome to the audit log. A failure to
+// write is logged rather than failing a request that has already been made.
+func (c *Client) recordMutation(ctx context.Context, r *Request, start time.Time, statusCode int, duration time.Duration, err error) {
+	record := audit.Record{
+		Time:       start.UTC(),
+		Method:     r.Method,
+		Endpoint:   r.Path,
+		Params:     redactValues(r.Params),
+		Status:     statusCode,
+		DurationMS: duration.Milliseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if err := c.audit.Append(ctx, record); err != nil {
+		c.logger.WithError(err).Error("Failed to write audit record")
+	}
+}
+
+// cancelOnClose releases a request's context once its body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // SetLogLevel sets the logging level
 func (c *Client) SetLogLevel(level logrus.Level) {
 	c.logger.SetLevel(level)
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ProjectKeysPerCall is the most project keys BulkDeleteProjects sends in
+// one request. SonarQube accepts at most 1,000 values in a list parameter,
+// and long lists of keys also run into limits on the request size.
+const ProjectKeysPerCall = 500
+
+// ProjectFilter selects projects for SearchProjectsByFilter and
+// BulkDeleteProjects. Set fields are combined with AND.
+type ProjectFilter struct {
+	// Projects limits the selection to these keys
+	Projects []string
+
+	// Query matches part of the project name or key
+	Query string
+
+	// AnalyzedBefore selects projects last analyzed before this date
+	// (yyyy-MM-dd) or datetime; projects never analyzed are not selected
+	AnalyzedBefore string
+
+	// OnProvisionedOnly selects projects that were never analyzed
+	OnProvisionedOnly bool
+}
+
+// ErrEmptyProjectFilter is returned by BulkDeleteProjects for a filter that
+// would select every project
+var ErrEmptyProjectFilter = errors.New("refusing to bulk delete projects without a filter")
+
+// IsEmpty reports whether f selects every project
+func (f ProjectFilter) IsEmpty() bool {
+	return len(f.Projects) == 0 && f.Query == "" && f.AnalyzedBefore == "" && !f.OnProvisionedOnly
+}
+
+func (f ProjectFilter) apply(req *Request) *Request {
+	req.SetList("projects", f.Projects).
+		SetIfNotEmpty("q", f.Query).
+		SetIfNotEmpty("analyzedBefore", f.AnalyzedBefore)
+	if f.OnProvisionedOnly {
+		req.Set("onProvisionedOnly", "true")
+	}
+	return req
+}
+
+// SearchProjectsByFilter streams the projects selected by filter, the same
+// ones BulkDeleteProjects would delete
+func (c *Client) SearchProjectsByFilter(ctx context.Context, filter ProjectFilter) *Iterator[Project] {
+	req := filter.apply(newRequest(http.MethodGet, "projects/search"))
+
+	return newIterator[Project](ctx, c, req, "components")
+}
+
+// BulkDeleteError is returned by BulkDeleteProjects when a request fails
+// after earlier ones deleted some of the listed projects
+type BulkDeleteError struct {
+	// Deleted are the listed keys sent in the requests that succeeded,
+	// whether or not they matched the rest of the filter
+	Deleted []string
+	Err     error
+}
+
+func (e *BulkDeleteError) Error() string {
+	return fmt.Sprintf("bulk delete failed after removing the first %d listed projects: %v", len(e.Deleted), e.Err)
+}
+
+func (e *BulkDeleteError) Unwrap() error {
+	return e.Err
+}
+
+// BulkDeleteProjects deletes every project selected by filter. Projects
+// are sent ProjectKeysPerCall keys at a time; a failure after the first
+// request is a *BulkDeleteError. An empty filter is rejected with
+// ErrEmptyProjectFilter, and SonarQube itself rejects a filter without
+// Projects, Query or AnalyzedBefore.
+func (c *Client) BulkDeleteProjects(ctx context.Context, filter ProjectFilter) error {
+	if filter.IsEmpty() {
+		return ErrEmptyProjectFilter
+	}
+
+	if len(filter.Projects) <= ProjectKeysPerCall {
+		return c.call(ctx, filter.apply(newRequest(http.MethodPost, "projects/bulk_delete")))
+	}
+
+	keys := filter.Projects
+	for start := 0; start < len(keys); start += ProjectKeysPerCall {
+		chunk := filter
+		chunk.Projects = keys[start:min(start+ProjectKeysPerCall, len(keys))]
+		if err := c.call(ctx, chunk.apply(newRequest(http.MethodPost, "projects/bulk_delete"))); err != nil {
+			if start == 0 {
+				return err
+			}
+			return &BulkDeleteError{Deleted: keys[:start], Err: err}
+		}
+	}
+	return nil
+}
+
+// KeyUpdate is one key change made or proposed by BulkUpdateProjectKey
+type KeyUpdate struct {
+	Key       string `json:"key"`
+	NewKey    string `json:"newKey"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// BulkUpdateProjectKey replaces from with to in the key of project and of
+// its modules. With dryRun set, nothing changes and the returned updates
+// show what would, including keys that would collide with existing ones.
+func (c *Client) BulkUpdateProjectKey(ctx context.Context, project, from, to string, dryRun bool) ([]KeyUpdate, error) {
+	req := newRequest(http.MethodPost, "projects/bulk_update_key").
+		Set("project", project).
+		Set("from", from).
+		Set("to", to).
+		Set("dryRun", strconv.FormatBool(dryRun))
+
+	result, err := doJSON[struct {
+		Keys []KeyUpdate `json:"keys"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Keys, nil
+}
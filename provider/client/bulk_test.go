@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBulkDeleteProjectsChunksKeys(t *testing.T) {
+	const total = 2*ProjectKeysPerCall + 200
+
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	keys := make([]string, total)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("svc-%04d", i)
+		fake.PutProject(fakesonar.Project{Key: keys[i], Name: keys[i]})
+	}
+	fake.PutProject(fakesonar.Project{Key: "svc-kept", Name: "Kept"})
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken)
+	ctx := context.Background()
+	require.NoError(t, c.BulkDeleteProjects(ctx, ProjectFilter{Projects: keys, Query: "svc-"}))
+
+	left, err := c.SearchProjects(ctx, "svc-").All()
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	assert.Equal(t, "svc-kept", left[0].Key)
+}
+
+func TestBulkDeleteProjectsReportsPartialProgress(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.NoError(t, r.ParseForm())
+		assert.LessOrEqual(t, len(strings.Split(r.Form.Get("projects"), ",")), ProjectKeysPerCall)
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":[{"msg":"Project is being deleted"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	keys := make([]string, 3*ProjectKeysPerCall)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("svc-%04d", i)
+	}
+
+	err := NewClient(srv.URL, "token").BulkDeleteProjects(context.Background(), ProjectFilter{Projects: keys})
+	var partial *BulkDeleteError
+	require.True(t, errors.As(err, &partial), "got %v", err)
+	assert.Equal(t, keys[:ProjectKeysPerCall], partial.Deleted)
+	assert.Contains(t, err.Error(), "Project is being deleted")
+	assert.Equal(t, 2, calls, "no request after the failed one")
+}
@@ -0,0 +1,350 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a cassette captures live traffic or serves
+// previously captured traffic
+type CassetteMode string
+
+const (
+	// CassetteRecord sends requests to the server and appends every
+	// request/response pair to the cassette
+	CassetteRecord CassetteMode = "record"
+
+	// CassetteReplay serves responses from the cassette without any network
+	// access
+	CassetteReplay CassetteMode = "replay"
+)
+
+// redacted replaces secrets before an interaction is written to disk
+const redacted = "REDACTED"
+
+var (
+	redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	redactedParams  = []string{"token", "password", "previousPassword"}
+
+	// redactedJSON matches token values in response bodies, such as the one
+	// returned by api/user_tokens/generate
+	redactedJSON = regexp.MustCompile(`"token"\s*:\s*"[^"]*"`)
+)
+
+// ErrNoRecordedInteraction is returned in replay mode when the cassette has
+// no response for a request
+var ErrNoRecordedInteraction = errors.New("no recorded interaction")
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `yaml:"request"`
+	Response RecordedResponse `yaml:"response"`
+}
+
+// RecordedRequest is a request as stored in a cassette. URL holds only the
+// path and query so cassettes replay against any host.
+type RecordedRequest struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette
+type RecordedResponse struct {
+	StatusCode int         `yaml:"status_code"`
+	Headers    http.Header `yaml:"headers,omitempty"`
+	Body       string      `yaml:"body,omitempty"`
+}
+
+// Cassette is a YAML file of recorded interactions. All clients in a
+// process that use the same path share one Cassette, so interactions are
+// recorded and replayed in the order the provider issued them.
+type Cassette struct {
+	Interactions []*Interaction `yaml:"interactions"`
+
+	path   string
+	mode   CassetteMode
+	mu     sync.Mutex
+	played map[int]bool
+	cursor int
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = map[cassetteConfig]*Cassette{}
+)
+
+// WithCassette records or replays all HTTP traffic using the cassette at
+// path. Recording appends to an existing cassette; remove the file to start
+// over. Tokens and passwords are redacted before anything is written.
+func WithCassette(path string, mode CassetteMode) ClientOption {
+	return func(c *Client) {
+		c.cassette = &cassetteConfig{path: path, mode: mode}
+	}
+}
+
+type cassetteConfig struct {
+	path string
+	mode CassetteMode
+}
+
+// openCassette returns the process-wide Cassette for path and mode, loading
+// it on first use
+func openCassette(path string, mode CassetteMode) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("invalid cassette mode %q, must be %q or %q", mode, CassetteRecord, CassetteReplay)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve cassette path: %w", err)
+	}
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	key := cassetteConfig{path: abs, mode: mode}
+	if cassette, ok := cassettes[key]; ok {
+		return cassette, nil
+	}
+
+	cassette := &Cassette{path: abs, mode: mode, played: map[int]bool{}}
+	data, err := os.ReadFile(abs)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && mode == CassetteRecord:
+	default:
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+
+	cassettes[key] = cassette
+	return cassette, nil
+}
+
+// record appends an interaction and rewrites the cassette file so nothing
+// is lost if the process exits without cleanup, as provider plugins do
+func (c *Cassette) record(interaction *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, interaction)
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// replay finds the response for req. It prefers the next unplayed match
+// after the last replayed interaction, then any unplayed match, and finally
+// the last match so repeated reads keep working.
+func (c *Cassette) replay(req *RecordedRequest) (*RecordedResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first, last := -1, -1
+	for i, interaction := range c.Interactions {
+		if !interaction.Request.matches(req) {
+			continue
+		}
+		last = i
+		if c.played[i] {
+			continue
+		}
+		if i >= c.cursor {
+			first = i
+			break
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+
+	match := first
+	if match < 0 {
+		match = last
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w for %s %s in cassette %s", ErrNoRecordedInteraction, req.Method, req.URL, c.path)
+	}
+
+	c.played[match] = true
+	c.cursor = match + 1
+	return &c.Interactions[match].Response, nil
+}
+
+func (r *RecordedRequest) matches(other *RecordedRequest) bool {
+	return r.Method == other.Method && r.URL == other.URL && r.Body == other.Body
+}
+
+// cassetteTransport records or replays requests below the retry and rate
+// limiting layers, so each attempt is its own interaction
+type cassetteTransport struct {
+	base     http.RoundTripper
+	cassette *Cassette
+	err      error
+}
+
+func newCassetteTransport(config *cassetteConfig, base http.RoundTripper) *cassetteTransport {
+	cassette, err := openCassette(config.path, config.mode)
+	return &cassetteTransport{base: base, cassette: cassette, err: err}
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.cassette.mode == CassetteReplay {
+		recordedResp, err := t.cassette.replay(recorded)
+		if err != nil {
+			return nil, err
+		}
+		return recordedResp.toResponse(req), nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: *recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       redactedJSON.ReplaceAllString(string(body), `"token":"`+redacted+`"`),
+		},
+	}
+	if err := t.cassette.record(interaction); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// recordRequest captures req in its redacted, host-independent form. The
+// request body is restored so it can still be sent.
+func recordRequest(req *http.Request) (*RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	u.RawQuery = redactParams(u.RawQuery)
+
+	recordedBody := string(body)
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		recordedBody = redactParams(recordedBody)
+	}
+
+	return &RecordedRequest{
+		Method:  req.Method,
+		URL:     u.RequestURI(),
+		Headers: redactHeaders(req.Header),
+		Body:    recordedBody,
+	}, nil
+}
+
+func (r *RecordedResponse) toResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range redactedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// redactParams replaces secret values in a URL-encoded query or form body.
+// Encoding sorts the parameters, so recorded and live requests compare
+// equal regardless of the order they were built in.
+func redactParams(encoded string) string {
+	if encoded == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return encoded
+	}
+	return redactValues(values).Encode()
+}
+
+// redactValues returns a copy of values with secrets replaced
+func redactValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for name, v := range values {
+		out[name] = append([]string(nil), v...)
+	}
+	for _, name := range redactedParams {
+		if _, ok := out[name]; ok {
+			out.Set(name, redacted)
+		}
+	}
+	// Settings whose key ends in .secured hold secrets such as OAuth client
+	// secrets
+	if strings.HasSuffix(out.Get("key"), ".secured") {
+		for _, name := range []string{"value", "values"} {
+			if _, ok := out[name]; ok {
+				out.Set(name, redacted)
+			}
+		}
+	}
+	return out
+}
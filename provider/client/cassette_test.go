@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.yaml")
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/api/projects/create":
+			_, _ = w.Write([]byte(`{"project":{"key":"demo","name":"Demo"}}`))
+		case "/api/projects/search":
+			_, _ = w.Write([]byte(`{"paging":{"pageIndex":1,"pageSize":500,"total":1},"components":[{"key":"demo","name":"Demo"}]}`))
+		case "/api/user_tokens/generate":
+			_, _ = w.Write([]byte(`{"login":"ci","name":"ci","token":"squ_live_secret"}`))
+		}
+	}))
+
+	recorder := NewClient(srv.URL, "super-secret-token", WithCassette(path, CassetteRecord))
+	_, err := recorder.CreateProject(context.Background(), "Demo", "demo", "private", "", nil)
+	require.NoError(t, err)
+	_, err = recorder.ReadProject(context.Background(), "demo")
+	require.NoError(t, err)
+	require.NoError(t, recorder.call(context.Background(),
+		newRequest(http.MethodPost, "user_tokens/generate").Set("name", "ci").Set("password", "hunter2")))
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "super-secret-token")
+	assert.NotContains(t, string(data), "squ_live_secret")
+	assert.NotContains(t, string(data), "hunter2")
+	assert.Contains(t, string(data), redacted)
+
+	// The server is gone, so everything must come from the cassette
+	replayer := NewClient("http://sonarqube.invalid", "another-token", WithCassette(path, CassetteReplay))
+	project, err := replayer.ReadProject(context.Background(), "demo")
+	require.NoError(t, err)
+	assert.Equal(t, "Demo", project.Name)
+	assert.Equal(t, 3, calls)
+
+	_, err = replayer.client.HTTPClient.Get("http://sonarqube.invalid/api/projects/search?projects=unknown")
+	assert.ErrorIs(t, err, ErrNoRecordedInteraction)
+}
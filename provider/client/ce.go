@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	defaultTaskPollMin = 500 * time.Millisecond
+	defaultTaskPollMax = 10 * time.Second
+)
+
+// TaskStatus is the state of a Compute Engine task
+type TaskStatus string
+
+const (
+	TaskPending    TaskStatus = "PENDING"
+	TaskInProgress TaskStatus = "IN_PROGRESS"
+	TaskSuccess    TaskStatus = "SUCCESS"
+	TaskFailed     TaskStatus = "FAILED"
+	TaskCanceled   TaskStatus = "CANCELED"
+)
+
+// Task is a Compute Engine task, the unit of background work SonarQube
+// uses for analysis reports, portfolio recalculation, large project
+// deletions and project imports
+type Task struct {
+	ID              string     `json:"id"`
+	Type            string     `json:"type"`
+	ComponentKey    string     `json:"componentKey,omitempty"`
+	Status          TaskStatus `json:"status"`
+	SubmittedAt     string     `json:"submittedAt,omitempty"`
+	ExecutedAt      string     `json:"executedAt,omitempty"`
+	ErrorMessage    string     `json:"errorMessage,omitempty"`
+	ErrorStacktrace string     `json:"errorStacktrace,omitempty"`
+}
+
+// Done reports whether the task has finished, successfully or not
+func (t *Task) Done() bool {
+	return t.Status != TaskPending && t.Status != TaskInProgress
+}
+
+// TaskError is returned when a Compute Engine task fails or is canceled
+type TaskError struct {
+	Task *Task
+}
+
+func (e *TaskError) Error() string {
+	msg := fmt.Sprintf("SonarQube %s task %s", e.Task.Type, e.Task.ID)
+	if e.Task.ComponentKey != "" {
+		msg += fmt.Sprintf(" for %q", e.Task.ComponentKey)
+	}
+	if e.Task.Status == TaskCanceled {
+		msg += " was canceled"
+	} else {
+		msg += " failed"
+	}
+	if e.Task.ErrorMessage != "" {
+		msg += ": " + e.Task.ErrorMessage
+	}
+	return msg
+}
+
+// WithTaskPolling sets the first and longest wait between polls of a
+// Compute Engine task. The wait doubles after every poll.
+func WithTaskPolling(min, max time.Duration) ClientOption {
+	return func(c *Client) {
+		c.taskPollMin = min
+		c.taskPollMax = max
+	}
+}
+
+// GetTask returns a Compute Engine task, including its stack trace if it
+// failed
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+	req := newRequest(http.MethodGet, "ce/task").
+		Set("id", id).
+		Set("additionalFields", "stacktrace")
+
+	resp, err := doJSON[struct {
+		Task Task `json:"task"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Task, nil
+}
+
+// ComponentQueue is the Compute Engine activity of a component: the tasks
+// waiting or running, and the most recently finished one
+type ComponentQueue struct {
+	Queue   []Task `json:"queue"`
+	Current *Task  `json:"current,omitempty"`
+}
+
+// GetComponentQueue returns the Compute Engine activity of a project,
+// application or portfolio
+func (c *Client) GetComponentQueue(ctx context.Context, component string) (*ComponentQueue, error) {
+	req := newRequest(http.MethodGet, "ce/component").
+		Set("component", component)
+
+	return doJSON[ComponentQueue](ctx, c, req)
+}
+
+// WaitForTask polls a Compute Engine task until it finishes. It returns a
+// TaskError if the task failed or was canceled, and ctx's error if ctx ends
+// first.
+func (c *Client) WaitForTask(ctx context.Context, id string) (*Task, error) {
+	var task *Task
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		task, err = c.GetTask(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return task.Done(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for Compute Engine task %s: %w", id, err)
+	}
+	if task.Status != TaskSuccess {
+		return task, &TaskError{Task: task}
+	}
+	return task, nil
+}
+
+// WaitForComponentQueue polls until no Compute Engine task is waiting or
+// running for component, then returns the most recently finished task, or
+// nil if the component never had one. It returns a TaskError if that task
+// failed or was canceled.
+func (c *Client) WaitForComponentQueue(ctx context.Context, component string) (*Task, error) {
+	var queue *ComponentQueue
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		queue, err = c.GetComponentQueue(ctx, component)
+		if err != nil {
+			return false, err
+		}
+		return len(queue.Queue) == 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for Compute Engine queue of %q: %w", component, err)
+	}
+
+	task := queue.Current
+	if task == nil {
+		return nil, nil
+	}
+	if task.Status == TaskFailed && task.ErrorStacktrace == "" {
+		// ce/component leaves out stack traces
+		if full, err := c.GetTask(ctx, task.ID); err == nil {
+			task = full
+		}
+	}
+	if task.Status != TaskSuccess {
+		return task, &TaskError{Task: task}
+	}
+	return task, nil
+}
+
+// poll calls check with exponential backoff until it reports done, fails,
+// or ctx ends
+func (c *Client) poll(ctx context.Context, check func() (bool, error)) error {
+	wait, max := c.taskPollMin, c.taskPollMax
+	if wait <= 0 {
+		wait = defaultTaskPollMin
+	}
+	if max <= 0 {
+		max = defaultTaskPollMax
+	}
+	if max < wait {
+		max = wait
+	}
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if wait *= 2; wait > max {
+			wait = max
+		}
+	}
+}
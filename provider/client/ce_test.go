@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitForTaskPollsUntilDone(t *testing.T) {
+	statuses := []string{"PENDING", "IN_PROGRESS", "SUCCESS"}
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/ce/task", r.URL.Path)
+		assert.Equal(t, "stacktrace", r.URL.Query().Get("additionalFields"))
+		status := statuses[polls]
+		polls++
+		fmt.Fprintf(w, `{"task":{"id":"AX1","type":"REPORT","status":%q}}`, status)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token", WithTaskPolling(time.Millisecond, 2*time.Millisecond))
+	task, err := c.WaitForTask(context.Background(), "AX1")
+	require.NoError(t, err)
+	assert.Equal(t, TaskSuccess, task.Status)
+	assert.Equal(t, 3, polls)
+}
+
+func TestWaitForComponentQueueReportsFailure(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	fake.PutProject(fakesonar.Project{Key: "svc", Name: "Service"})
+	fake.FailTasks("platform", "Portfolio has too many projects", "java.lang.IllegalStateException\n\tat ...")
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithTaskPolling(time.Millisecond, time.Millisecond))
+	ctx := context.Background()
+	require.NoError(t, c.CreatePortfolio(ctx, &Portfolio{Key: "platform", Name: "Platform", Selection: PortfolioSelection{Mode: "MANUAL", Projects: []string{"svc"}}}))
+
+	_, err := c.WaitForComponentQueue(ctx, "platform")
+	var taskErr *TaskError
+	require.True(t, errors.As(err, &taskErr), "got %v", err)
+	assert.Contains(t, err.Error(), "Portfolio has too many projects")
+	assert.Contains(t, taskErr.Task.ErrorStacktrace, "IllegalStateException", "stack trace fetched from ce/task")
+	assert.Equal(t, ErrorTypeServer, ClassifyError(err))
+}
+
+func TestWaitForComponentQueueHonoursContext(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	fake.PutTask(fakesonar.Task{Type: "REPORT", ComponentKey: "svc", Status: "IN_PROGRESS"})
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithTaskPolling(time.Millisecond, 5*time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.WaitForComponentQueue(ctx, "svc")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
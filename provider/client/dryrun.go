@@ -0,0 +1,947 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Change is a mutating API call intercepted in dry-run mode
+type Change struct {
+	ResourceType string              `json:"resource_type,omitempty"`
+	ResourceID   string              `json:"resource_id,omitempty"`
+	Operation    string              `json:"operation,omitempty"`
+	Method       string              `json:"method"`
+	Endpoint     string              `json:"endpoint"`
+	Params       map[string][]string `json:"params,omitempty"`
+}
+
+func (ch Change) String() string {
+	return fmt.Sprintf("%s api/%s %s", ch.Method, ch.Endpoint, url.Values(ch.Params).Encode())
+}
+
+// DryRun intercepts every POST a client would send and records it instead.
+// The intercepted calls are applied to an in-memory overlay of projects,
+// quality gates, portfolios, users, groups and group members, and reads of
+// those objects are answered from the overlay, so a create followed by a
+// read in the same run sees the object it created. GETs for anything else
+// go to the server.
+//
+// Objects are copied from the server into the overlay the first time a call
+// changes them. Searches other than lookups by key are not overlaid.
+type DryRun struct {
+	mu         sync.Mutex
+	changes    []Change
+	nextID     int
+	projects   map[string]*Project
+	gates      map[string]*QualityGate
+	portfolios map[string]*Portfolio
+	users      map[string]*User
+	groups     map[string]*Group
+	members    map[string]map[string]GroupMember
+
+	deletedConditions map[string]bool
+}
+
+// NewDryRun creates an empty overlay
+func NewDryRun() *DryRun {
+	return &DryRun{
+		projects:   map[string]*Project{},
+		gates:      map[string]*QualityGate{},
+		portfolios: map[string]*Portfolio{},
+		users:      map[string]*User{},
+		groups:     map[string]*Group{},
+		members:    map[string]map[string]GroupMember{},
+
+		deletedConditions: map[string]bool{},
+	}
+}
+
+// WithDryRun intercepts mutating calls with dryRun instead of sending them
+func WithDryRun(dryRun *DryRun) ClientOption {
+	return func(c *Client) {
+		c.dryRun = dryRun
+	}
+}
+
+// Changes returns the intercepted calls in the order they were made
+func (d *DryRun) Changes() []Change {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Change(nil), d.changes...)
+}
+
+type changeCollectorKey struct{}
+
+type changeCollector struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// CollectChanges returns a context whose intercepted calls are also
+// collected separately, and a function returning those collected so far.
+// It lets a caller report the calls made on its behalf.
+func CollectChanges(ctx context.Context) (context.Context, func() []Change) {
+	collector := &changeCollector{}
+	return context.WithValue(ctx, changeCollectorKey{}, collector), func() []Change {
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		return append([]Change(nil), collector.changes...)
+	}
+}
+
+// dryRunHandler answers an intercepted call. It returns the value to send
+// back as the JSON response body, nil for an empty 204, or handled false
+// to send a GET to the server.
+type dryRunHandler func(d *DryRun, ctx context.Context, c *Client, params url.Values) (body interface{}, handled bool, err error)
+
+// The handler tables are filled in by init, as the handlers reach
+// doRequest, which consults the tables
+var dryRunMutations, dryRunReads map[string]dryRunHandler
+
+func init() {
+	dryRunMutations = map[string]dryRunHandler{
+		"projects/create":                (*DryRun).createProject,
+		"projects/update":                (*DryRun).updateProject,
+		"projects/delete":                (*DryRun).deleteProject,
+		"projects/bulk_delete":           (*DryRun).bulkDeleteProjects,
+		"qualitygates/create":            (*DryRun).createQualityGate,
+		"qualitygates/rename":            (*DryRun).renameQualityGate,
+		"qualitygates/destroy":           (*DryRun).destroyQualityGate,
+		"qualitygates/create_condition":  (*DryRun).createQualityGateCondition,
+		"qualitygates/delete_condition":  (*DryRun).deleteQualityGateCondition,
+		"portfolios/create":              (*DryRun).createPortfolio,
+		"portfolios/update":              (*DryRun).updatePortfolio,
+		"portfolios/delete":              (*DryRun).deletePortfolio,
+		"portfolios/configure_selection": (*DryRun).configurePortfolioSelection,
+		"users/create":                   (*DryRun).createUser,
+		"users/update":                   (*DryRun).updateUser,
+		"users/update_login":             (*DryRun).updateUserLogin,
+		"users/deactivate":               (*DryRun).deactivateUser,
+		"user_groups/create":             (*DryRun).createGroup,
+		"user_groups/update":             (*DryRun).updateGroup,
+		"user_groups/delete":             (*DryRun).deleteGroup,
+		"user_groups/add_user":           (*DryRun).addGroupMember,
+		"user_groups/remove_user":        (*DryRun).removeGroupMember,
+
+		"POST v2/authorizations/groups":        (*DryRun).createGroupV2,
+		"PATCH v2/authorizations/groups/{id}":  (*DryRun).updateGroupV2,
+		"DELETE v2/authorizations/groups/{id}": (*DryRun).deleteGroupV2,
+	}
+
+	dryRunReads = map[string]dryRunHandler{
+		"projects/search":    (*DryRun).searchProjects,
+		"qualitygates/show":  (*DryRun).showQualityGate,
+		"portfolios/show":    (*DryRun).showPortfolio,
+		"ce/component":       (*DryRun).componentQueue,
+		"users/search":       (*DryRun).searchUsers,
+		"user_groups/search": (*DryRun).searchGroups,
+		"user_groups/users":  (*DryRun).searchGroupMembers,
+	}
+}
+
+// serverReadKey marks a context whose reads bypass the overlay, for read
+// handlers that load server state into it
+type serverReadKey struct{}
+
+// intercept answers r from the overlay. It returns false for GETs the
+// overlay knows nothing about. Mutations are looked up by path, or by method
+// and path for v2 endpoints that take several methods.
+func (d *DryRun) intercept(ctx context.Context, c *Client, r *Request) (*http.Response, bool, error) {
+	if r.Method == http.MethodGet && ctx.Value(serverReadKey{}) != nil {
+		return nil, false, nil
+	}
+
+	handler := dryRunReads[r.Path]
+	if r.Method != http.MethodGet {
+		d.record(ctx, r)
+		handler = dryRunMutations[r.Path]
+		if h, ok := dryRunMutations[r.Method+" "+r.Path]; ok {
+			handler = h
+		}
+		if handler == nil {
+			return synthesizedResponse(r, nil)
+		}
+	}
+	if handler == nil {
+		return nil, false, nil
+	}
+
+	body, handled, err := handler(d, ctx, c, r.Params)
+	if err != nil || !handled {
+		return nil, handled, err
+	}
+	return synthesizedResponse(r, body)
+}
+
+func (d *DryRun) record(ctx context.Context, r *Request) {
+	change := Change{
+		Method:   r.Method,
+		Endpoint: r.Path,
+		Params:   redactValues(r.Params),
+	}
+	change.ResourceType, change.ResourceID, change.Operation = audit.ResourceFromContext(ctx)
+
+	d.mu.Lock()
+	d.changes = append(d.changes, change)
+	d.mu.Unlock()
+
+	if collector, ok := ctx.Value(changeCollectorKey{}).(*changeCollector); ok {
+		collector.mu.Lock()
+		collector.changes = append(collector.changes, change)
+		collector.mu.Unlock()
+	}
+}
+
+func synthesizedResponse(r *Request, body interface{}) (*http.Response, bool, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusNoContent,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+	}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to encode dry-run %s response: %w", r.Path, err)
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Header.Set("Content-Type", "application/json")
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	return resp, true, nil
+}
+
+func (d *DryRun) syntheticID() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	return fmt.Sprintf("dry-run-%d", d.nextID)
+}
+
+func splitParam(params url.Values, key string) []string {
+	if v := params.Get(key); v != "" {
+		return strings.Split(v, ",")
+	}
+	return []string{}
+}
+
+// Projects
+
+// project returns the overlay copy of key, copying it from the server on
+// first use
+func (d *DryRun) project(ctx context.Context, c *Client, key string) (*Project, error) {
+	d.mu.Lock()
+	p, ok := d.projects[key]
+	d.mu.Unlock()
+	if !ok {
+		var err error
+		if p, err = c.ReadProject(ctx, key); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		if existing, ok := d.projects[key]; ok {
+			p = existing
+		} else {
+			d.projects[key] = p
+		}
+		d.mu.Unlock()
+	}
+	if p == nil {
+		return nil, newNotFoundError("projects/search", "project not found: %s", key)
+	}
+	return p, nil
+}
+
+func (d *DryRun) createProject(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	p := &Project{
+		Key:        params.Get("project"),
+		Name:       params.Get("name"),
+		Visibility: params.Get("visibility"),
+		MainBranch: params.Get("mainBranch"),
+		Tags:       splitParam(params, "tags"),
+		Qualifier:  "TRK",
+	}
+
+	d.mu.Lock()
+	d.projects[p.Key] = p
+	d.mu.Unlock()
+
+	return map[string]interface{}{"project": p}, true, nil
+}
+
+func (d *DryRun) updateProject(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	p, err := d.project(ctx, c, params.Get("project"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if name := params.Get("name"); name != "" {
+		p.Name = name
+	}
+	if visibility := params.Get("visibility"); visibility != "" {
+		p.Visibility = visibility
+	}
+	if _, ok := params["tags"]; ok {
+		p.Tags = splitParam(params, "tags")
+	}
+	return nil, true, nil
+}
+
+func (d *DryRun) deleteProject(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	d.projects[params.Get("project")] = nil
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+// bulkDeleteProjects marks the listed projects deleted. Deletes selected
+// only by the other filters are recorded but not overlaid.
+func (d *DryRun) bulkDeleteProjects(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	for _, key := range strings.Split(params.Get("projects"), ",") {
+		if key != "" {
+			d.projects[key] = nil
+		}
+	}
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+// searchProjects answers lookups of a single key, as made by ReadProject
+func (d *DryRun) searchProjects(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	key := params.Get("projects")
+	if key == "" || strings.Contains(key, ",") {
+		return nil, false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.projects[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	components := []Project{}
+	if p != nil {
+		components = append(components, *p)
+	}
+	return map[string]interface{}{
+		"paging":     Paging{PageIndex: 1, PageSize: defaultPageSize, Total: len(components)},
+		"components": components,
+	}, true, nil
+}
+
+// Quality gates are kept under the identifier the client addresses them by,
+// which is the name on current servers and the numeric id on old ones
+
+func (d *DryRun) qualityGate(ctx context.Context, c *Client, gate string) (*QualityGate, error) {
+	d.mu.Lock()
+	g, ok := d.gates[gate]
+	d.mu.Unlock()
+	if !ok {
+		var err error
+		if g, err = c.ReadQualityGate(ctx, gate); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		if existing, ok := d.gates[gate]; ok {
+			g = existing
+		} else {
+			g.Conditions = d.withoutDeletedConditions(g.Conditions)
+			d.gates[gate] = g
+		}
+		d.mu.Unlock()
+	}
+	if g == nil {
+		return nil, newNotFoundError("qualitygates/show", "quality gate not found: %s", gate)
+	}
+	return g, nil
+}
+
+func (d *DryRun) createQualityGate(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g := &QualityGate{Name: params.Get("name"), Conditions: []Condition{}}
+	g.ID = g.Name
+	if !c.supports(CapQualityGateByName) {
+		g.ID = d.syntheticID()
+	}
+
+	d.mu.Lock()
+	d.gates[g.ID] = g
+	d.mu.Unlock()
+
+	return g, true, nil
+}
+
+func (d *DryRun) renameQualityGate(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	current := params.Get(c.qualityGateParam("currentName", "id"))
+	g, err := d.qualityGate(ctx, c, current)
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g.Name = params.Get("name")
+	if c.supports(CapQualityGateByName) {
+		d.gates[current] = nil
+		g.ID = g.Name
+		d.gates[g.ID] = g
+	}
+	return nil, true, nil
+}
+
+func (d *DryRun) destroyQualityGate(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	d.gates[params.Get(c.qualityGateParam("name", "id"))] = nil
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+func (d *DryRun) createQualityGateCondition(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g, err := d.qualityGate(ctx, c, params.Get(c.qualityGateParam("gateName", "gateId")))
+	if err != nil {
+		return nil, true, err
+	}
+
+	condition := Condition{
+		ID:     d.syntheticID(),
+		Metric: params.Get("metric"),
+		Op:     params.Get("op"),
+		Error:  params.Get("error"),
+	}
+
+	d.mu.Lock()
+	g.Conditions = append(g.Conditions, condition)
+	d.mu.Unlock()
+
+	return condition, true, nil
+}
+
+// deleteQualityGateCondition drops the condition from whichever gate holds
+// it. delete_condition only takes the condition id, so the id is also
+// remembered for gates that are read from the server later.
+func (d *DryRun) deleteQualityGateCondition(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deletedConditions[params.Get("id")] = true
+	for _, g := range d.gates {
+		if g != nil {
+			g.Conditions = d.withoutDeletedConditions(g.Conditions)
+		}
+	}
+	return nil, true, nil
+}
+
+// withoutDeletedConditions must be called with d.mu held
+func (d *DryRun) withoutDeletedConditions(conditions []Condition) []Condition {
+	kept := make([]Condition, 0, len(conditions))
+	for _, condition := range conditions {
+		if !d.deletedConditions[condition.ID] {
+			kept = append(kept, condition)
+		}
+	}
+	return kept
+}
+
+func (d *DryRun) showQualityGate(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	gate := params.Get(c.qualityGateParam("name", "id"))
+
+	d.mu.Lock()
+	g, ok := d.gates[gate]
+	pending := len(d.deletedConditions) > 0
+	d.mu.Unlock()
+	if !ok {
+		if !pending {
+			return nil, false, nil
+		}
+		// Load the gate into the overlay, so deleted conditions are hidden
+		if _, err := d.qualityGate(context.WithValue(ctx, serverReadKey{}, true), c, gate); err != nil {
+			return nil, true, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g = d.gates[gate]
+	if g == nil {
+		return nil, true, newNotFoundError("qualitygates/show", "quality gate not found: %s", gate)
+	}
+	cp := *g
+	cp.Conditions = append([]Condition{}, g.Conditions...)
+	return &cp, true, nil
+}
+
+// Portfolios
+
+func (d *DryRun) portfolio(ctx context.Context, c *Client, key string) (*Portfolio, error) {
+	d.mu.Lock()
+	p, ok := d.portfolios[key]
+	d.mu.Unlock()
+	if !ok {
+		var err error
+		if p, err = c.GetPortfolio(ctx, key); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		if existing, ok := d.portfolios[key]; ok {
+			p = existing
+		} else {
+			d.portfolios[key] = p
+		}
+		d.mu.Unlock()
+	}
+	if p == nil {
+		return nil, newNotFoundError("portfolios/show", "portfolio not found: %s", key)
+	}
+	return p, nil
+}
+
+func (d *DryRun) createPortfolio(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	p := &Portfolio{
+		Key:         params.Get("key"),
+		Name:        params.Get("name"),
+		Description: params.Get("description"),
+		Selection:   PortfolioSelection{Mode: "NONE"},
+	}
+
+	d.mu.Lock()
+	d.portfolios[p.Key] = p
+	d.mu.Unlock()
+
+	return nil, true, nil
+}
+
+func (d *DryRun) updatePortfolio(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	p, err := d.portfolio(ctx, c, params.Get("key"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p.Name = params.Get("name")
+	p.Description = params.Get("description")
+	return nil, true, nil
+}
+
+func (d *DryRun) deletePortfolio(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	d.portfolios[params.Get("key")] = nil
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+func (d *DryRun) configurePortfolioSelection(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	p, err := d.portfolio(ctx, c, params.Get("key"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p.Selection = PortfolioSelection{
+		Mode:           params.Get("mode"),
+		ProjectPattern: params.Get("projectPattern"),
+		BranchPattern:  params.Get("branchPattern"),
+	}
+	if p.Selection.Mode == "MANUAL" {
+		p.Selection.Projects = splitParam(params, "projects")
+	}
+	return nil, true, nil
+}
+
+func (d *DryRun) showPortfolio(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	key := params.Get("key")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.portfolios[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if p == nil {
+		return nil, true, newNotFoundError("portfolios/show", "portfolio not found: %s", key)
+	}
+	cp := *p
+	return &cp, true, nil
+}
+
+// componentQueue reports an idle Compute Engine queue, as intercepted calls
+// never submit tasks
+func (d *DryRun) componentQueue(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	return &ComponentQueue{Queue: []Task{}}, true, nil
+}
+
+// Users are kept by login. Deactivated users are nil, so lookups of them
+// report not found.
+
+func (d *DryRun) user(ctx context.Context, c *Client, login string) (*User, error) {
+	d.mu.Lock()
+	u, ok := d.users[login]
+	d.mu.Unlock()
+	if !ok {
+		var err error
+		if u, err = c.GetUser(ctx, login); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		if existing, ok := d.users[login]; ok {
+			u = existing
+		} else {
+			d.users[login] = u
+		}
+		d.mu.Unlock()
+	}
+	if u == nil {
+		return nil, newNotFoundError("users/search", "user not found: %s", login)
+	}
+	return u, nil
+}
+
+func (d *DryRun) createUser(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	u := &User{
+		Login:       params.Get("login"),
+		Name:        params.Get("name"),
+		Email:       params.Get("email"),
+		Active:      true,
+		Local:       params.Get("local") != "false",
+		ScmAccounts: scmAccountParams(params),
+	}
+
+	d.mu.Lock()
+	d.users[u.Login] = u
+	d.mu.Unlock()
+
+	cp := *u
+	return map[string]interface{}{"user": &cp}, true, nil
+}
+
+func (d *DryRun) updateUser(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	u, err := d.user(ctx, c, params.Get("login"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := params["name"]; ok {
+		u.Name = params.Get("name")
+	}
+	if _, ok := params["email"]; ok {
+		u.Email = params.Get("email")
+	}
+	if _, ok := params["scmAccount"]; ok {
+		u.ScmAccounts = scmAccountParams(params)
+	}
+	cp := *u
+	return map[string]interface{}{"user": &cp}, true, nil
+}
+
+func (d *DryRun) updateUserLogin(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	u, err := d.user(ctx, c, params.Get("login"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users[u.Login] = nil
+	u.Login = params.Get("newLogin")
+	d.users[u.Login] = u
+	return nil, true, nil
+}
+
+func (d *DryRun) deactivateUser(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	d.users[params.Get("login")] = nil
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+// searchUsers answers searches for active users whose query is a login in
+// the overlay, as made by GetUser
+func (d *DryRun) searchUsers(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	if params.Get("deactivated") == "true" {
+		return nil, false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	u, ok := d.users[params.Get("q")]
+	if !ok {
+		return nil, false, nil
+	}
+
+	users := []User{}
+	if u != nil {
+		users = append(users, *u)
+	}
+	return map[string]interface{}{
+		"paging": Paging{PageIndex: 1, PageSize: defaultPageSize, Total: len(users)},
+		"users":  users,
+	}, true, nil
+}
+
+func scmAccountParams(params url.Values) []string {
+	accounts := []string{}
+	for _, account := range params["scmAccount"] {
+		if account != "" {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
+// Groups are kept by name. Deleted groups are nil.
+
+func (d *DryRun) group(ctx context.Context, c *Client, name string) (*Group, error) {
+	d.mu.Lock()
+	g, ok := d.groups[name]
+	d.mu.Unlock()
+	if !ok {
+		var err error
+		if g, err = c.GetGroup(ctx, name); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		if existing, ok := d.groups[name]; ok {
+			g = existing
+		} else {
+			d.groups[name] = g
+		}
+		d.mu.Unlock()
+	}
+	if g == nil {
+		return nil, newNotFoundError("user_groups/search", "group not found: %s", name)
+	}
+	return g, nil
+}
+
+// groupByID finds the group the v2 API addresses by id, in the overlay or
+// on the server
+func (d *DryRun) groupByID(ctx context.Context, c *Client, id string) (*Group, error) {
+	d.mu.Lock()
+	for _, g := range d.groups {
+		if g != nil && g.ID == id {
+			d.mu.Unlock()
+			return g, nil
+		}
+	}
+	d.mu.Unlock()
+
+	groups, err := c.SearchGroups(ctx, "").All()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if g.ID == id {
+			return d.group(ctx, c, g.Name)
+		}
+	}
+	return nil, newNotFoundError("user_groups/search", "group not found: %s", id)
+}
+
+func (d *DryRun) addGroup(params url.Values) *Group {
+	g := &Group{
+		ID:          d.syntheticID(),
+		Name:        params.Get("name"),
+		Description: params.Get("description"),
+	}
+
+	d.mu.Lock()
+	d.groups[g.Name] = g
+	d.mu.Unlock()
+	return g
+}
+
+// changeGroup applies a rename and description change to g
+func (d *DryRun) changeGroup(g *Group, params url.Values) Group {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if name := params.Get("name"); name != "" && name != g.Name {
+		d.groups[g.Name] = nil
+		if members, ok := d.members[g.Name]; ok {
+			delete(d.members, g.Name)
+			d.members[name] = members
+		}
+		g.Name = name
+		d.groups[name] = g
+	}
+	if _, ok := params["description"]; ok {
+		g.Description = params.Get("description")
+	}
+	return *g
+}
+
+func (d *DryRun) createGroup(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g := *d.addGroup(params)
+	return map[string]interface{}{"group": &g}, true, nil
+}
+
+func (d *DryRun) createGroupV2(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g := *d.addGroup(params)
+	return &g, true, nil
+}
+
+func (d *DryRun) updateGroup(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g, err := d.group(ctx, c, params.Get("currentName"))
+	if err != nil {
+		return nil, true, err
+	}
+	updated := d.changeGroup(g, params)
+	return map[string]interface{}{"group": &updated}, true, nil
+}
+
+func (d *DryRun) updateGroupV2(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g, err := d.groupByID(ctx, c, params.Get("id"))
+	if err != nil {
+		return nil, true, err
+	}
+	updated := d.changeGroup(g, params)
+	return &updated, true, nil
+}
+
+func (d *DryRun) deleteGroup(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	d.groups[params.Get("name")] = nil
+	delete(d.members, params.Get("name"))
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+func (d *DryRun) deleteGroupV2(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	g, err := d.groupByID(ctx, c, params.Get("id"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	d.groups[g.Name] = nil
+	delete(d.members, g.Name)
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+// searchGroups answers searches whose query is a group name in the
+// overlay, as made by GetGroup
+func (d *DryRun) searchGroups(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g, ok := d.groups[params.Get("q")]
+	if !ok {
+		return nil, false, nil
+	}
+
+	groups := []Group{}
+	if g != nil {
+		groups = append(groups, *g)
+	}
+	return map[string]interface{}{
+		"paging": Paging{PageIndex: 1, PageSize: defaultPageSize, Total: len(groups)},
+		"groups": groups,
+	}, true, nil
+}
+
+// Group members are kept by group name, then login
+
+// groupMembers returns the overlay members of group, copying them from the
+// server on first use
+func (d *DryRun) groupMembers(ctx context.Context, c *Client, group string) (map[string]GroupMember, error) {
+	if _, err := d.group(ctx, c, group); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	members, ok := d.members[group]
+	d.mu.Unlock()
+	if ok {
+		return members, nil
+	}
+
+	list, err := c.SearchGroupMembers(ctx, group, "").All()
+	if err != nil {
+		return nil, err
+	}
+	members = map[string]GroupMember{}
+	for _, member := range list {
+		members[member.Login] = member
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.members[group]; ok {
+		return existing, nil
+	}
+	d.members[group] = members
+	return members, nil
+}
+
+func (d *DryRun) addGroupMember(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	members, err := d.groupMembers(ctx, c, params.Get("name"))
+	if err != nil {
+		return nil, true, err
+	}
+	u, err := d.user(ctx, c, params.Get("login"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	members[u.Login] = GroupMember{Login: u.Login, Name: u.Name}
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+func (d *DryRun) removeGroupMember(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	members, err := d.groupMembers(ctx, c, params.Get("name"))
+	if err != nil {
+		return nil, true, err
+	}
+
+	d.mu.Lock()
+	delete(members, params.Get("login"))
+	d.mu.Unlock()
+	return nil, true, nil
+}
+
+// searchGroupMembers answers member searches of groups whose members
+// changed in the overlay, on a single page
+func (d *DryRun) searchGroupMembers(ctx context.Context, c *Client, params url.Values) (interface{}, bool, error) {
+	if selected := params.Get("selected"); selected != "" && selected != "selected" {
+		return nil, false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	group := params.Get("name")
+	members, ok := d.members[group]
+	if !ok {
+		return nil, false, nil
+	}
+	if d.groups[group] == nil {
+		return nil, true, newNotFoundError("user_groups/users", "group not found: %s", group)
+	}
+
+	query := strings.ToLower(params.Get("q"))
+	users := []GroupMember{}
+	for _, member := range members {
+		if strings.Contains(strings.ToLower(member.Login), query) || strings.Contains(strings.ToLower(member.Name), query) {
+			users = append(users, member)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Login < users[j].Login })
+	return map[string]interface{}{
+		"paging": Paging{PageIndex: 1, PageSize: defaultPageSize, Total: len(users)},
+		"users":  users,
+	}, true, nil
+}
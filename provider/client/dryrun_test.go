@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestDryRunOverlaysMutations(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	fake.PutProject(fakesonar.Project{Key: "existing", Name: "Existing"})
+
+	dryRun := NewDryRun()
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(dryRun))
+	ctx := context.Background()
+
+	_, err := c.CreateProject(ctx, "New", "new", "private", "main", []string{"go"})
+	require.NoError(t, err)
+	created, err := c.ReadProject(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "New", created.Name)
+	assert.Nil(t, fake.Project("new"), "create reached the server")
+
+	updated, err := c.UpdateProject(ctx, "existing", "Renamed", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+	assert.Equal(t, "Existing", fake.Project("existing").Name, "update reached the server")
+
+	require.NoError(t, c.DeleteProject(ctx, "existing"))
+	_, err = c.ReadProject(ctx, "existing")
+	assert.True(t, IsNotFound(err))
+	assert.NotNil(t, fake.Project("existing"), "delete reached the server")
+
+	var endpoints []string
+	for _, change := range dryRun.Changes() {
+		endpoints = append(endpoints, change.Endpoint)
+	}
+	assert.Equal(t, []string{"projects/create", "projects/update", "projects/delete"}, endpoints)
+}
+
+func TestDryRunQualityGateRename(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(NewDryRun()))
+	ctx := context.Background()
+
+	gate, err := c.CreateQualityGate(ctx, "Gate")
+	require.NoError(t, err)
+	_, err = c.CreateQualityGateCondition(ctx, gate.ID, "coverage", "LT", "80")
+	require.NoError(t, err)
+
+	renamed, err := c.UpdateQualityGate(ctx, gate.ID, "Renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", renamed.ID)
+	require.Len(t, renamed.Conditions, 1)
+	assert.Equal(t, "coverage", renamed.Conditions[0].Metric)
+
+	_, err = c.ReadQualityGate(ctx, "Gate")
+	assert.True(t, IsNotFound(err))
+	assert.Nil(t, fake.QualityGate("Renamed"))
+}
+
+func TestDryRunQualityGateDeleteCondition(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	ctx := context.Background()
+
+	live := NewClient(fake.URL, fakesonar.DefaultToken)
+	gate, err := live.CreateQualityGate(ctx, "Gate")
+	require.NoError(t, err)
+	coverage, err := live.CreateQualityGateCondition(ctx, gate.ID, "coverage", "LT", "80")
+	require.NoError(t, err)
+	_, err = live.CreateQualityGateCondition(ctx, gate.ID, "duplicated_lines_density", "GT", "3")
+	require.NoError(t, err)
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(NewDryRun()))
+	require.NoError(t, c.DeleteQualityGateCondition(ctx, coverage.ID))
+
+	read, err := c.ReadQualityGate(ctx, gate.ID)
+	require.NoError(t, err)
+	require.Len(t, read.Conditions, 1, "the deleted condition stays gone on read")
+	assert.Equal(t, "duplicated_lines_density", read.Conditions[0].Metric)
+	assert.Len(t, fake.QualityGate("Gate").Conditions, 2, "delete did not reach the server")
+
+	added, err := c.CreateQualityGateCondition(ctx, gate.ID, "coverage", "LT", "90")
+	require.NoError(t, err)
+	require.NoError(t, c.DeleteQualityGateCondition(ctx, added.ID))
+	read, err = c.ReadQualityGate(ctx, gate.ID)
+	require.NoError(t, err)
+	assert.Len(t, read.Conditions, 1, "a condition created in the overlay can be deleted too")
+}
+
+func TestDryRunUsers(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	fake.PutUser(fakesonar.User{Login: "existing", Name: "Existing", Active: true, Local: true})
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(NewDryRun()))
+	ctx := context.Background()
+
+	_, err := c.CreateUser(ctx, &User{Login: "new", Name: "New", Local: true}, "s3cret-pass")
+	require.NoError(t, err)
+	created, err := c.GetUser(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "New", created.Name)
+	assert.Nil(t, fake.User("new"), "create reached the server")
+
+	require.NoError(t, c.UpdateUserLogin(ctx, "existing", "renamed"))
+	renamed, err := c.GetUser(ctx, "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "Existing", renamed.Name)
+	assert.NotNil(t, fake.User("existing"), "rename reached the server")
+
+	require.NoError(t, c.DeactivateUser(ctx, "renamed"))
+	_, err = c.GetUser(ctx, "renamed")
+	assert.True(t, IsNotFound(err))
+}
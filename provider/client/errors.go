@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned for any SonarQube Web API call that completes with a
+// 4xx or 5xx status code
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	Messages   []string
+}
+
+// apiErrorBody matches the error payload SonarQube returns, e.g.
+// {"errors":[{"msg":"Project 'foo' not found"}]}
+type apiErrorBody struct {
+	Errors []struct {
+		Msg string `json:"msg"`
+	} `json:"errors"`
+
+	// Message is how the v2 API reports errors
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("SonarQube API %s %s failed with status %d", e.Method, e.Endpoint, e.StatusCode)
+	if len(e.Messages) > 0 {
+		msg += ": " + strings.Join(e.Messages, "; ")
+	}
+	return msg
+}
+
+// newAPIError builds an APIError from a failed response body, keeping the raw
+// body as the message if it is not SonarQube's JSON error format
+func newAPIError(method, endpoint string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		Endpoint:   endpoint,
+	}
+
+	var parsed apiErrorBody
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		for _, e := range parsed.Errors {
+			apiErr.Messages = append(apiErr.Messages, e.Msg)
+		}
+	} else if err == nil && parsed.Message != "" {
+		apiErr.Messages = []string{parsed.Message}
+	} else if raw := strings.TrimSpace(string(body)); raw != "" {
+		apiErr.Messages = []string{raw}
+	}
+
+	return apiErr
+}
+
+// newNotFoundError is used by lookups that SonarQube answers with an empty
+// search result rather than a 404
+func newNotFoundError(endpoint, format string, args ...interface{}) *APIError {
+	return &APIError{
+		StatusCode: http.StatusNotFound,
+		Method:     http.MethodGet,
+		Endpoint:   endpoint,
+		Messages:   []string{fmt.Sprintf(format, args...)},
+	}
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// IsNotFound reports whether err is a SonarQube 404
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is a SonarQube 409, returned when a key or
+// name is already in use
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsForbidden reports whether err is a SonarQube 403
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsUnauthorized reports whether err is a SonarQube 401
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// Error types returned by ClassifyError, used as metric labels
+const (
+	ErrorTypeAuth     = "auth"
+	ErrorTypeNotFound = "not_found"
+	ErrorTypeConflict = "conflict"
+	ErrorTypeClient   = "client"
+	ErrorTypeServer   = "server"
+	ErrorTypeNetwork  = "network"
+	ErrorTypeTimeout  = "timeout"
+	ErrorTypeOther    = "other"
+)
+
+// ClassifyError maps err to one of the ErrorType constants. SonarQube reports
+// most duplicate keys and names as a 400 "already exists", so those count as
+// conflicts too.
+func ClassifyError(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ErrorTypeAuth
+		case apiErr.StatusCode == http.StatusNotFound:
+			return ErrorTypeNotFound
+		case apiErr.StatusCode == http.StatusConflict || apiErr.isAlreadyExists():
+			return ErrorTypeConflict
+		case apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusGatewayTimeout:
+			return ErrorTypeTimeout
+		case apiErr.StatusCode >= 500:
+			return ErrorTypeServer
+		default:
+			return ErrorTypeClient
+		}
+	}
+
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return ErrorTypeServer
+	}
+
+	var oauth2Err *OAuth2Error
+	if errors.As(err, &oauth2Err) && oauth2Err.StatusCode < 500 {
+		return ErrorTypeAuth
+	}
+
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return ErrorTypeTimeout
+	case errors.As(err, &netErr) || errors.Is(err, ErrNoRecordedInteraction):
+		return ErrorTypeNetwork
+	}
+	return ErrorTypeOther
+}
+
+func (e *APIError) isAlreadyExists() bool {
+	if e.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, msg := range e.Messages {
+		if strings.Contains(strings.ToLower(msg), "already exists") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want []string
+	}{
+		"sonarqube errors": {`{"errors":[{"msg":"Project 'foo' not found"},{"msg":"second"}]}`, []string{"Project 'foo' not found", "second"}},
+		"v2 message":       {`{"message":"Group not found"}`, []string{"Group not found"}},
+		"non-JSON body":    {"<html>Bad Gateway</html>\n", []string{"<html>Bad Gateway</html>"}},
+		"JSON without msg": {`{"status":"DOWN"}`, []string{`{"status":"DOWN"}`}},
+		"empty body":       {"", nil},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newAPIError(http.MethodGet, "projects/search", http.StatusNotFound, []byte(tc.body))
+			assert.Equal(t, http.StatusNotFound, err.StatusCode)
+			assert.Equal(t, "projects/search", err.Endpoint)
+			assert.Equal(t, tc.want, err.Messages)
+		})
+	}
+
+	err := newAPIError(http.MethodPost, "projects/create", http.StatusBadRequest, []byte(`{"errors":[{"msg":"a"},{"msg":"b"}]}`))
+	assert.EqualError(t, err, "SonarQube API POST projects/create failed with status 400: a; b")
+}
+
+func TestAPIErrorStatus(t *testing.T) {
+	for status, is := range map[int]func(error) bool{
+		http.StatusNotFound:     IsNotFound,
+		http.StatusConflict:     IsConflict,
+		http.StatusForbidden:    IsForbidden,
+		http.StatusUnauthorized: IsUnauthorized,
+	} {
+		err := fmt.Errorf("reading project: %w", newAPIError(http.MethodGet, "projects/search", status, nil))
+		assert.True(t, is(err), "status %d through a wrapped error", status)
+		assert.False(t, is(newAPIError(http.MethodGet, "projects/search", http.StatusInternalServerError, nil)), "status %d against a 500", status)
+	}
+	assert.False(t, IsNotFound(fmt.Errorf("connection refused")))
+}
+
+func TestClientReturnsAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"msg":"Component key 'demo' not found"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token")
+	_, err := c.ReadProject(context.Background(), "demo")
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, []string{"Component key 'demo' not found"}, apiErr.Messages)
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{&APIError{StatusCode: 401}, ErrorTypeAuth},
+		{&APIError{StatusCode: 403}, ErrorTypeAuth},
+		{newNotFoundError("projects/search", "project not found: %s", "demo"), ErrorTypeNotFound},
+		{&APIError{StatusCode: 409}, ErrorTypeConflict},
+		{&APIError{StatusCode: 400, Messages: []string{"Could not create Project with key: \"demo\". A similar key already exists: \"demo\""}}, ErrorTypeConflict},
+		{&APIError{StatusCode: 400, Messages: []string{"The 'name' parameter is missing"}}, ErrorTypeClient},
+		{&APIError{StatusCode: 502}, ErrorTypeServer},
+		{fmt.Errorf("request failed: %w", &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}), ErrorTypeNetwork},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), ErrorTypeTimeout},
+		{fmt.Errorf("failed to decode response"), ErrorTypeOther},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, ClassifyError(tc.err), tc.err.Error())
+	}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Group represents a SonarQube user group
+type Group struct {
+	ID           string `json:"id,omitempty"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	MembersCount int    `json:"membersCount"`
+	Default      bool   `json:"default"`
+}
+
+// SearchGroups streams every group whose name contains query, or all groups
+// when query is empty. Searches use api/user_groups/search on every version,
+// as 10.x still serves it and returns the ids the v2 API takes.
+func (c *Client) SearchGroups(ctx context.Context, query string) *Iterator[Group] {
+	req := newRequest(http.MethodGet, "user_groups/search").
+		SetIfNotEmpty("q", query)
+
+	return newIterator[Group](ctx, c, req, "groups")
+}
+
+// GetGroup looks up a single group by exact name
+func (c *Client) GetGroup(ctx context.Context, name string) (*Group, error) {
+	it := c.SearchGroups(ctx, name)
+	for it.Next() {
+		if group := it.Item(); group.Name == name {
+			return &group, nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, newNotFoundError("user_groups/search", "group not found: %s", name)
+}
+
+// groupResponse is the body returned by api/user_groups/create and update
+type groupResponse struct {
+	Group Group `json:"group"`
+}
+
+// CreateGroup creates a group. Servers from 10.5 on use the v2 API.
+func (c *Client) CreateGroup(ctx context.Context, name, description string) (*Group, error) {
+	if c.supports(CapGroupsV2) {
+		req := newRequest(http.MethodPost, "v2/authorizations/groups").
+			Set("name", name).
+			SetIfNotEmpty("description", description).
+			AsJSON()
+
+		return doJSON[Group](ctx, c, req)
+	}
+
+	req := newRequest(http.MethodPost, "user_groups/create").
+		Set("name", name).
+		SetIfNotEmpty("description", description)
+
+	result, err := doJSON[groupResponse](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.Group, nil
+}
+
+// UpdateGroup renames the group currentName to name and replaces its
+// description, keeping its members and permissions
+func (c *Client) UpdateGroup(ctx context.Context, currentName, name, description string) (*Group, error) {
+	if c.supports(CapGroupsV2) {
+		group, err := c.GetGroup(ctx, currentName)
+		if err != nil {
+			return nil, err
+		}
+
+		req := newRequest(http.MethodPatch, "v2/authorizations/groups/{id}").
+			Set("id", group.ID).
+			Set("description", description).
+			AsJSON()
+		if name != currentName {
+			req.Set("name", name)
+		}
+
+		return doJSON[Group](ctx, c, req)
+	}
+
+	req := newRequest(http.MethodPost, "user_groups/update").
+		Set("currentName", currentName).
+		Set("description", description)
+	if name != currentName {
+		req.Set("name", name)
+	}
+
+	result, err := doJSON[groupResponse](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.Group, nil
+}
+
+// DeleteGroup deletes a group. SonarQube refuses to delete the default
+// group and the last group holding the administer permission.
+func (c *Client) DeleteGroup(ctx context.Context, name string) error {
+	if c.supports(CapGroupsV2) {
+		group, err := c.GetGroup(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		req := newRequest(http.MethodDelete, "v2/authorizations/groups/{id}").
+			Set("id", group.ID)
+
+		return c.call(ctx, req)
+	}
+
+	req := newRequest(http.MethodPost, "user_groups/delete").
+		Set("name", name)
+
+	return c.call(ctx, req)
+}
+
+// GroupMember is a user belonging to a group
+type GroupMember struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+// SearchGroupMembers streams the members of a group whose login or name
+// contains query, or all members when query is empty
+func (c *Client) SearchGroupMembers(ctx context.Context, group, query string) *Iterator[GroupMember] {
+	req := newRequest(http.MethodGet, "user_groups/users").
+		Set("name", group).
+		Set("selected", "selected").
+		SetIfNotEmpty("q", query)
+
+	return newIterator[GroupMember](ctx, c, req, "users")
+}
+
+// AddGroupMember adds a user to a group. Adding a member twice is a no-op.
+func (c *Client) AddGroupMember(ctx context.Context, group, login string) error {
+	req := newRequest(http.MethodPost, "user_groups/add_user").
+		Set("name", group).
+		Set("login", login)
+
+	return c.call(ctx, req)
+}
+
+// RemoveGroupMember removes a user from a group. SonarQube refuses to
+// remove members of the default group.
+func (c *Client) RemoveGroupMember(ctx context.Context, group, login string) error {
+	req := newRequest(http.MethodPost, "user_groups/remove_user").
+		Set("name", group).
+		Set("login", login)
+
+	return c.call(ctx, req)
+}
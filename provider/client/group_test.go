@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestGroupLifecycle(t *testing.T) {
+	for _, version := range []string{"9.9.1.69595", "10.5.0.89998"} {
+		t.Run(version, func(t *testing.T) {
+			fake := fakesonar.NewServer(fakesonar.WithVersion(version))
+			defer fake.Close()
+			c := NewClient(fake.URL, fakesonar.DefaultToken)
+			ctx := context.Background()
+			_, err := c.DetectServer(ctx)
+			require.NoError(t, err)
+
+			created, err := c.CreateGroup(ctx, "developers", "Developers")
+			require.NoError(t, err)
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, "Developers", created.Description)
+
+			renamed, err := c.UpdateGroup(ctx, "developers", "engineers", "")
+			require.NoError(t, err)
+			assert.Equal(t, created.ID, renamed.ID, "renaming keeps the group")
+			assert.Nil(t, fake.Group("developers"))
+			assert.Equal(t, "", fake.Group("engineers").Description)
+
+			_, err = c.CreateGroup(ctx, "engineers", "")
+			assert.Equal(t, ErrorTypeConflict, ClassifyError(err))
+
+			require.NoError(t, c.DeleteGroup(ctx, "engineers"))
+			assert.Nil(t, fake.Group("engineers"))
+
+			err = c.DeleteGroup(ctx, "sonar-users")
+			assert.ErrorContains(t, err, "Default group 'sonar-users' cannot be used")
+		})
+	}
+}
+
+func TestDryRunGroupsV2(t *testing.T) {
+	fake := fakesonar.NewServer(fakesonar.WithVersion("10.5.0.89998"))
+	defer fake.Close()
+
+	dryRun := NewDryRun()
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(dryRun))
+	ctx := context.Background()
+	_, err := c.DetectServer(ctx)
+	require.NoError(t, err)
+
+	_, err = c.UpdateGroup(ctx, "sonar-administrators", "admins", "Admins")
+	require.NoError(t, err)
+	renamed, err := c.GetGroup(ctx, "admins")
+	require.NoError(t, err)
+	assert.Equal(t, "Admins", renamed.Description)
+	assert.NotNil(t, fake.Group("sonar-administrators"), "rename reached the server")
+
+	require.NoError(t, c.DeleteGroup(ctx, "admins"))
+	_, err = c.GetGroup(ctx, "admins")
+	assert.True(t, IsNotFound(err))
+
+	var changes []string
+	for _, change := range dryRun.Changes() {
+		changes = append(changes, change.Method+" "+change.Endpoint)
+	}
+	assert.Equal(t, []string{"PATCH v2/authorizations/groups/{id}", "DELETE v2/authorizations/groups/{id}"}, changes)
+}
+
+func TestGroupMembers(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	c := NewClient(fake.URL, fakesonar.DefaultToken)
+	ctx := context.Background()
+	_, err := c.DetectServer(ctx)
+	require.NoError(t, err)
+
+	_, err = c.CreateGroup(ctx, "developers", "")
+	require.NoError(t, err)
+	for _, login := range []string{"alice", "bob", "carol"} {
+		_, err := c.CreateUser(ctx, &User{Login: login, Name: login, Local: true}, "s3cret-pass")
+		require.NoError(t, err)
+		require.NoError(t, c.AddGroupMember(ctx, "developers", login))
+	}
+	require.NoError(t, c.AddGroupMember(ctx, "developers", "alice"), "adding a member twice is a no-op")
+	require.NoError(t, c.RemoveGroupMember(ctx, "developers", "bob"))
+
+	members, err := c.SearchGroupMembers(ctx, "developers", "").WithPageSize(1).All()
+	require.NoError(t, err)
+	var logins []string
+	for _, member := range members {
+		logins = append(logins, member.Login)
+	}
+	assert.ElementsMatch(t, []string{"alice", "carol"}, logins)
+
+	err = c.AddGroupMember(ctx, "missing", "alice")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestDryRunGroupMembers(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	setup := NewClient(fake.URL, fakesonar.DefaultToken)
+	ctx := context.Background()
+	_, err := setup.DetectServer(ctx)
+	require.NoError(t, err)
+	_, err = setup.CreateGroup(ctx, "developers", "")
+	require.NoError(t, err)
+	_, err = setup.CreateUser(ctx, &User{Login: "alice", Name: "Alice", Local: true}, "s3cret-pass")
+	require.NoError(t, err)
+	require.NoError(t, setup.AddGroupMember(ctx, "developers", "alice"))
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken, WithDryRun(NewDryRun()))
+	require.NoError(t, c.AddGroupMember(ctx, "developers", "admin"))
+	require.NoError(t, c.RemoveGroupMember(ctx, "developers", "alice"))
+
+	members, err := c.SearchGroupMembers(ctx, "developers", "").All()
+	require.NoError(t, err)
+	assert.Equal(t, []GroupMember{{Login: "admin", Name: "Administrator"}}, members)
+	assert.Equal(t, map[string]bool{"alice": true}, fake.Group("developers").Members, "nothing reached the server")
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Metric represents a SonarQube metric definition
+type Metric struct {
+	ID          string `json:"id,omitempty"`
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Type        string `json:"type"`
+	Custom      bool   `json:"custom"`
+	Hidden      bool   `json:"hidden"`
+}
+
+// SearchMetrics streams every metric definition
+func (c *Client) SearchMetrics(ctx context.Context) *Iterator[Metric] {
+	return newIterator[Metric](ctx, c, newRequest(http.MethodGet, "metrics/search"), "metrics")
+}
+
+// GetMetric looks up a single metric by key
+func (c *Client) GetMetric(ctx context.Context, key string) (*Metric, error) {
+	it := c.SearchMetrics(ctx)
+	for it.Next() {
+		if metric := it.Item(); metric.Key == key {
+			return &metric, nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, newNotFoundError("metrics/search", "metric not found: %s", key)
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOAuth2Header carries the proxy's access token, leaving
+// Authorization for the SonarQube token
+const DefaultOAuth2Header = "Proxy-Authorization"
+
+// oauth2ExpiryWindow is how long before expiry a token is refreshed
+const oauth2ExpiryWindow = 30 * time.Second
+
+// OAuth2Config describes the client-credentials grant used to get access
+// tokens for an OAuth2-protected proxy in front of SonarQube
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Audience is sent as the audience parameter, which some identity
+	// providers require to pick the token's recipient
+	Audience string
+
+	// Header is the request header that carries "Bearer <access token>".
+	// Empty means DefaultOAuth2Header.
+	Header string
+}
+
+// WithOAuth2 sends an access token from cfg's token endpoint with every
+// request, alongside the SonarQube credentials. The token is fetched on the
+// first request, refreshed shortly before it expires, and refreshed and the
+// request replayed once when the proxy rejects it with a 401 carrying a
+// Bearer challenge. A 401 from SonarQube itself is passed through.
+func WithOAuth2(cfg OAuth2Config) ClientOption {
+	return func(c *Client) {
+		if cfg.Header == "" {
+			cfg.Header = DefaultOAuth2Header
+		}
+		c.oauth2 = &oauth2Source{config: cfg}
+	}
+}
+
+// OAuth2Error is returned when the token endpoint refuses to issue a token
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("OAuth2 token request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// oauth2Source caches the current access token
+type oauth2Source struct {
+	config OAuth2Config
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a cached token that is not about to expire, or fetches a new
+// one. A token equal to stale is never returned, so a token the proxy
+// rejected is replaced, but only once by concurrent callers.
+func (s *oauth2Source) Token(ctx context.Context, stale string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.token != stale && (s.expiry.IsZero() || time.Now().Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	token, lifetime, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s.token, s.expiry = token, time.Time{}
+	if lifetime > 0 {
+		window := oauth2ExpiryWindow
+		if window > lifetime/2 {
+			window = lifetime / 2
+		}
+		s.expiry = time.Now().Add(lifetime - window)
+	}
+	return token, nil
+}
+
+// expired reports whether token is the cached token and has reached its
+// refresh time
+func (s *oauth2Source) expired(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return token == s.token && !s.expiry.IsZero() && !time.Now().Before(s.expiry)
+}
+
+func (s *oauth2Source) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	if s.config.Audience != "" {
+		form.Set("audience", s.config.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create OAuth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("OAuth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read OAuth2 token response: %w", err)
+	}
+	_ = json.Unmarshal(raw, &body)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, &OAuth2Error{StatusCode: resp.StatusCode, Code: body.Error, Description: body.ErrorDescription}
+	}
+	if body.AccessToken == "" {
+		return "", 0, fmt.Errorf("OAuth2 token response has no access_token")
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported OAuth2 token type %q", body.TokenType)
+	}
+
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
+
+// oauth2Transport adds the access token to each request and replays a
+// request the proxy rejected with 401 once with a fresh token
+type oauth2Transport struct {
+	base   http.RoundTripper
+	source *oauth2Source
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := t.source.Token(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, body, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if !bearerChallenge(resp.Header) && !t.source.expired(token) {
+		return resp, nil
+	}
+
+	token, err = t.source.Token(req.Context(), token)
+	if err != nil {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return t.send(req, body, token)
+}
+
+func (t *oauth2Transport) send(req *http.Request, body []byte, token string) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	r.Header.Set(t.source.config.Header, "Bearer "+token)
+	return t.base.RoundTrip(r)
+}
+
+// bearerChallenge reports whether a 401 asks for a new bearer token, as an
+// OAuth2 proxy does, rather than coming from SonarQube
+func bearerChallenge(h http.Header) bool {
+	for _, challenge := range h.Values("WWW-Authenticate") {
+		scheme, _, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			return true
+		}
+	}
+	return false
+}
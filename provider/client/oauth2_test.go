@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// oauth2TestEnv is a stand-in token endpoint and an OAuth2-protected proxy
+// in front of SonarQube
+type oauth2TestEnv struct {
+	tokenURL string
+	proxyURL string
+
+	mu         sync.Mutex
+	expiresIn  int
+	sonarToken string
+	issued     int
+	valid      map[string]bool
+	forms      []string
+}
+
+func newOAuth2TestEnv(t *testing.T, expiresIn int) *oauth2TestEnv {
+	t.Helper()
+	env := &oauth2TestEnv{expiresIn: expiresIn, sonarToken: "sonar-token", valid: map[string]bool{}}
+
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		id, secret, _ := r.BasicAuth()
+		env.mu.Lock()
+		defer env.mu.Unlock()
+		env.forms = append(env.forms, r.PostForm.Encode())
+		w.Header().Set("Content-Type", "application/json")
+		if id != "terraform" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"unknown client"}`))
+			return
+		}
+		env.issued++
+		token := fmt.Sprintf("access-%d", env.issued)
+		env.valid[token] = true
+		_, _ = fmt.Fprintf(w, `{"access_token":%q,"token_type":"Bearer","expires_in":%d}`, token, env.expiresIn)
+	}))
+	t.Cleanup(idp.Close)
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env.mu.Lock()
+		ok := len(r.Header.Get("Proxy-Authorization")) > 7 && env.valid[r.Header.Get("Proxy-Authorization")[7:]]
+		sonarToken := env.sonarToken
+		env.mu.Unlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+sonarToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "demo", r.FormValue("project"), "the request body is replayed")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(proxy.Close)
+
+	env.tokenURL, env.proxyURL = idp.URL, proxy.URL
+	return env
+}
+
+func (env *oauth2TestEnv) client(secret string) *Client {
+	return NewClient(env.proxyURL, "sonar-token",
+		WithOAuth2(OAuth2Config{
+			TokenURL:     env.tokenURL,
+			ClientID:     "terraform",
+			ClientSecret: secret,
+			Scopes:       []string{"sonarqube", "openid"},
+			Audience:     "https://sonarqube.example.com",
+		}),
+		WithRetryConfig(RetryConfig{MaxRetries: 2, WaitMin: time.Millisecond, WaitMax: time.Millisecond}))
+}
+
+func (env *oauth2TestEnv) revokeAll() {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.valid = map[string]bool{}
+}
+
+func (env *oauth2TestEnv) issuedTokens() int {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	return env.issued
+}
+
+func TestOAuth2TokenIsCached(t *testing.T) {
+	env := newOAuth2TestEnv(t, 3600)
+	c := env.client("s3cret")
+	ctx := context.Background()
+
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+	assert.Equal(t, 1, env.issuedTokens())
+	assert.Equal(t, []string{"audience=https%3A%2F%2Fsonarqube.example.com&grant_type=client_credentials&scope=sonarqube+openid"}, env.forms)
+}
+
+func TestOAuth2RefreshesNearExpiry(t *testing.T) {
+	env := newOAuth2TestEnv(t, 1)
+	c := env.client("s3cret")
+	ctx := context.Background()
+
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+	time.Sleep(600 * time.Millisecond)
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+	assert.Equal(t, 2, env.issuedTokens(), "a token within half its one second lifetime of expiry is replaced")
+}
+
+func TestOAuth2RefreshesOnUnauthorized(t *testing.T) {
+	env := newOAuth2TestEnv(t, 3600)
+	c := env.client("s3cret")
+	ctx := context.Background()
+
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+	env.revokeAll()
+	require.NoError(t, c.DeleteProject(ctx, "demo"))
+
+	assert.Equal(t, 2, env.issuedTokens())
+}
+
+func TestOAuth2PassesSonarQubeUnauthorizedThrough(t *testing.T) {
+	env := newOAuth2TestEnv(t, 3600)
+	env.sonarToken = "rotated"
+	c := env.client("s3cret")
+
+	err := c.DeleteProject(context.Background(), "demo")
+	assert.True(t, IsUnauthorized(err), "got %v", err)
+	assert.Equal(t, 1, env.issuedTokens(), "a 401 without a Bearer challenge keeps the access token")
+}
+
+func TestOAuth2TokenEndpointErrors(t *testing.T) {
+	env := newOAuth2TestEnv(t, 3600)
+
+	err := env.client("wrong").DeleteProject(context.Background(), "demo")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "OAuth2 token request failed with status 401: invalid_client: unknown client")
+	assert.Equal(t, ErrorTypeAuth, ClassifyError(err))
+	assert.Len(t, env.forms, 1, "rejected client credentials are not retried")
+}
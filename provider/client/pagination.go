@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// defaultPageSize is the largest page size every search endpoint accepts
+	defaultPageSize = 500
+
+	// maxSearchResults is the hard cap SonarQube puts on p*ps for search
+	// endpoints backed by Elasticsearch. Endpoints backed by the database,
+	// such as projects/search and user_groups/search, page past it.
+	maxSearchResults = 10000
+)
+
+// Paging is the paging block returned by SonarQube search endpoints
+type Paging struct {
+	PageIndex int `json:"pageIndex"`
+	PageSize  int `json:"pageSize"`
+	Total     int `json:"total"`
+}
+
+// ErrSearchResultsCapped is returned by an Iterator over an
+// Elasticsearch-backed search whose query matches more than 10,000 results
+// even under one of its narrowing filters
+type ErrSearchResultsCapped struct {
+	Path  string
+	Total int
+}
+
+func (e *ErrSearchResultsCapped) Error() string {
+	return fmt.Sprintf("%s matched %d results, more than the %d SonarQube can page through; narrow the query",
+		e.Path, e.Total, maxSearchResults)
+}
+
+// Iterator streams the results of a paginated SonarQube search, fetching
+// pages lazily as Next is called:
+//
+//	it := c.SearchProjects(ctx, "")
+//	for it.Next() {
+//		project := it.Item()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	ctx      context.Context
+	client   *Client
+	req      *Request
+	field    string
+	pageSize int
+
+	// narrowing filters used when the base query exceeds maxSearchResults
+	narrowing  func(context.Context) ([]url.Values, error)
+	partitions []url.Values
+	partition  int
+	key        func(T) string
+	seen       map[string]struct{}
+
+	page int
+	buf  []T
+	cur  T
+	done bool
+	err  error
+}
+
+// newIterator creates an iterator over req. field is the JSON attribute
+// holding the result list, e.g. "components" for projects/search.
+func newIterator[T any](ctx context.Context, c *Client, req *Request, field string) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:       ctx,
+		client:    c,
+		req:       req,
+		field:     field,
+		pageSize:  defaultPageSize,
+		partition: -1,
+	}
+}
+
+// NewSliceIterator returns an Iterator over items that makes no requests,
+// for implementations of the service interfaces that are not backed by a
+// SonarQube server, such as test doubles
+func NewSliceIterator[T any](items []T) *Iterator[T] {
+	return &Iterator[T]{
+		buf:       append([]T(nil), items...),
+		done:      true,
+		partition: -1,
+	}
+}
+
+// WithPageSize overrides the number of results requested per page
+func (it *Iterator[T]) WithPageSize(size int) *Iterator[T] {
+	it.pageSize = size
+	return it
+}
+
+// WithNarrowing marks the search as capped at 10,000 results, as
+// Elasticsearch-backed searches such as rules/search are, and registers
+// filters that are applied one at a time on top of the base query when it
+// matches more. Together the filters should cover the whole result set;
+// results seen under more than one filter are de-duplicated using key.
+// filters is only called once the cap is actually hit. Searches without
+// narrowing are paged through to the end.
+func (it *Iterator[T]) WithNarrowing(key func(T) string, filters func(context.Context) ([]url.Values, error)) *Iterator[T] {
+	it.key = key
+	it.narrowing = filters
+	return it
+}
+
+// Next advances to the next result, fetching another page if needed. It
+// returns false when the results are exhausted, the context is canceled or
+// a request fails; check Err afterwards.
+func (it *Iterator[T]) Next() bool {
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.fetch()
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Item returns the current result
+func (it *Iterator[T]) Item() T {
+	return it.cur
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All drains the iterator into a slice
+func (it *Iterator[T]) All() ([]T, error) {
+	var items []T
+	for it.Next() {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
+
+func (it *Iterator[T]) fetch() {
+	it.page++
+	items, paging, err := fetchPage[T](it.ctx, it.client, it.pageRequest(), it.field)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	if paging.Total > maxSearchResults && it.page == 1 && it.narrowing != nil {
+		if it.partition >= 0 {
+			it.err = &ErrSearchResultsCapped{Path: it.req.Path, Total: paging.Total}
+			return
+		}
+
+		// Restart under the first narrowing filter
+		it.partitions, it.err = it.narrowing(it.ctx)
+		if it.err == nil && len(it.partitions) == 0 {
+			it.err = &ErrSearchResultsCapped{Path: it.req.Path, Total: paging.Total}
+		}
+		it.partition, it.page = 0, 0
+		it.seen = map[string]struct{}{}
+		return
+	}
+
+	it.buf = it.dedupe(items)
+
+	if len(items) < it.pageSize || it.page*it.pageSize >= paging.Total {
+		it.nextPartition()
+	}
+}
+
+// nextPartition moves to the next narrowing filter, or finishes iteration
+func (it *Iterator[T]) nextPartition() {
+	if it.partition < 0 || it.partition+1 >= len(it.partitions) {
+		it.done = true
+		return
+	}
+	it.partition++
+	it.page = 0
+}
+
+func (it *Iterator[T]) dedupe(items []T) []T {
+	if it.seen == nil || it.key == nil {
+		return items
+	}
+
+	unique := items[:0]
+	for _, item := range items {
+		k := it.key(item)
+		if _, ok := it.seen[k]; ok {
+			continue
+		}
+		it.seen[k] = struct{}{}
+		unique = append(unique, item)
+	}
+	return unique
+}
+
+func (it *Iterator[T]) pageRequest() *Request {
+	req := newRequest(it.req.Method, it.req.Path)
+	for k, v := range it.req.Params {
+		req.Params[k] = append([]string(nil), v...)
+	}
+	if it.partition >= 0 {
+		for k, v := range it.partitions[it.partition] {
+			req.Params[k] = append([]string(nil), v...)
+		}
+	}
+
+	return req.
+		Set("p", strconv.Itoa(it.page)).
+		Set("ps", strconv.Itoa(it.pageSize))
+}
+
+// fetchPage requests a single page and extracts the result list and paging
+// information. Older endpoints such as rules/search and metrics/search
+// report paging as top-level p/ps/total attributes instead of a paging block.
+func fetchPage[T any](ctx context.Context, c *Client, req *Request, field string) ([]T, Paging, error) {
+	raw, err := doJSON[map[string]json.RawMessage](ctx, c, req)
+	if err != nil {
+		return nil, Paging{}, err
+	}
+
+	var items []T
+	if data, ok := (*raw)[field]; ok {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, Paging{}, fmt.Errorf("failed to decode %s %s: %w", req.Path, field, err)
+		}
+	}
+
+	var paging Paging
+	if data, ok := (*raw)["paging"]; ok {
+		if err := json.Unmarshal(data, &paging); err != nil {
+			return nil, Paging{}, fmt.Errorf("failed to decode %s paging: %w", req.Path, err)
+		}
+	} else if total, ok := (*raw)["total"]; ok {
+		_ = json.Unmarshal(total, &paging.Total)
+		_ = json.Unmarshal((*raw)["p"], &paging.PageIndex)
+		_ = json.Unmarshal((*raw)["ps"], &paging.PageSize)
+	} else {
+		// Unpaginated endpoint, everything came back in one response
+		paging.Total = len(items)
+	}
+
+	return items, paging, nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestIteratorWalksAllPages(t *testing.T) {
+	const total = 1203
+	var pagesServed int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		ps, _ := strconv.Atoi(r.URL.Query().Get("ps"))
+		pagesServed++
+
+		var components []Project
+		for i := (p - 1) * ps; i < p*ps && i < total; i++ {
+			components = append(components, Project{Key: fmt.Sprintf("project-%d", i)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"paging":     Paging{PageIndex: p, PageSize: ps, Total: total},
+			"components": components,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token")
+	projects, err := c.SearchProjects(context.Background(), "").All()
+	require.NoError(t, err)
+
+	assert.Len(t, projects, total)
+	assert.Equal(t, "project-1202", projects[total-1].Key)
+	assert.Equal(t, 3, pagesServed)
+}
+
+func TestIteratorNarrowsPastResultCap(t *testing.T) {
+	rulesByLang := map[string]int{"java": 6000, "py": 5000}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/languages/list" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"languages": []Language{{Key: "java"}, {Key: "py"}},
+			})
+			return
+		}
+
+		p, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		ps, _ := strconv.Atoi(r.URL.Query().Get("ps"))
+		lang := r.URL.Query().Get("languages")
+
+		total := rulesByLang["java"] + rulesByLang["py"]
+		if lang != "" {
+			total = rulesByLang[lang]
+		}
+
+		var rules []Rule
+		for i := (p - 1) * ps; i < p*ps && i < total; i++ {
+			rules = append(rules, Rule{Key: fmt.Sprintf("%s:S%d", lang, i), Language: lang})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"total": total, "p": p, "ps": ps, "rules": rules,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token")
+	rules, err := c.SearchRules(context.Background(), "").All()
+	require.NoError(t, err)
+	assert.Len(t, rules, 11000)
+}
+
+func TestIteratorPagesPastResultCapOnDatabaseSearches(t *testing.T) {
+	const total = maxSearchResults + 50
+
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	fake.PutGroup(fakesonar.Group{Name: "everyone"})
+	for i := 0; i < total; i++ {
+		fake.PutProject(fakesonar.Project{Key: fmt.Sprintf("svc-%05d", i), Name: fmt.Sprintf("Service %d", i)})
+		fake.PutUser(fakesonar.User{Login: fmt.Sprintf("user-%05d", i), Name: fmt.Sprintf("User %d", i), Active: true})
+		fake.PutGroup(fakesonar.Group{Name: fmt.Sprintf("group-%05d", i)})
+		fake.AddGroupMember("everyone", fmt.Sprintf("user-%05d", i))
+	}
+
+	c := NewClient(fake.URL, fakesonar.DefaultToken)
+	ctx := context.Background()
+	_, err := c.DetectServer(ctx)
+	require.NoError(t, err)
+
+	projects, err := c.SearchProjects(ctx, "").All()
+	require.NoError(t, err)
+	assert.Len(t, projects, total)
+
+	users, err := c.SearchUsers(ctx, "user-").All()
+	require.NoError(t, err)
+	assert.Len(t, users, total)
+
+	groups, err := c.SearchGroups(ctx, "group-").All()
+	require.NoError(t, err)
+	assert.Len(t, groups, total)
+
+	members, err := c.SearchGroupMembers(ctx, "everyone", "").All()
+	require.NoError(t, err)
+	assert.Len(t, members, total)
+}
+
+func TestIteratorStopsOnCanceledContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("no request expected after cancellation")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := NewClient(srv.URL, "token").SearchUsers(ctx, "")
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
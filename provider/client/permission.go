@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// UserPermissions lists the permissions a user holds, globally or on a
+// project
+type UserPermissions struct {
+	Login       string   `json:"login"`
+	Name        string   `json:"name,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// GroupPermissions lists the permissions a group holds, globally or on a
+// project
+type GroupPermissions struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// permissionRequest addresses a global permission when project is empty and
+// a project permission otherwise
+func permissionRequest(method, path, project, permission string) *Request {
+	return newRequest(method, path).
+		SetIfNotEmpty("projectKey", project).
+		SetIfNotEmpty("permission", permission)
+}
+
+// AddUserPermission grants permission to login, globally when project is
+// empty
+func (c *Client) AddUserPermission(ctx context.Context, project, login, permission string) error {
+	req := permissionRequest(http.MethodPost, "permissions/add_user", project, permission).
+		Set("login", login)
+
+	return c.call(ctx, req)
+}
+
+// RemoveUserPermission revokes permission from login, globally when project
+// is empty
+func (c *Client) RemoveUserPermission(ctx context.Context, project, login, permission string) error {
+	req := permissionRequest(http.MethodPost, "permissions/remove_user", project, permission).
+		Set("login", login)
+
+	return c.call(ctx, req)
+}
+
+// AddGroupPermission grants permission to group, globally when project is
+// empty. "anyone" addresses the pseudo-group of all users.
+func (c *Client) AddGroupPermission(ctx context.Context, project, group, permission string) error {
+	req := permissionRequest(http.MethodPost, "permissions/add_group", project, permission).
+		Set("groupName", group)
+
+	return c.call(ctx, req)
+}
+
+// RemoveGroupPermission revokes permission from group, globally when project
+// is empty
+func (c *Client) RemoveGroupPermission(ctx context.Context, project, group, permission string) error {
+	req := permissionRequest(http.MethodPost, "permissions/remove_group", project, permission).
+		Set("groupName", group)
+
+	return c.call(ctx, req)
+}
+
+// SearchUserPermissions streams every user holding a permission, globally
+// when project is empty
+func (c *Client) SearchUserPermissions(ctx context.Context, project string) *Iterator[UserPermissions] {
+	req := permissionRequest(http.MethodGet, "permissions/users", project, "")
+
+	// permissions/users rejects page sizes above 100
+	return newIterator[UserPermissions](ctx, c, req, "users").WithPageSize(100)
+}
+
+// SearchGroupPermissions streams every group holding a permission, globally
+// when project is empty
+func (c *Client) SearchGroupPermissions(ctx context.Context, project string) *Iterator[GroupPermissions] {
+	req := permissionRequest(http.MethodGet, "permissions/groups", project, "")
+
+	return newIterator[GroupPermissions](ctx, c, req, "groups").WithPageSize(100)
+}
@@ -1,10 +1,9 @@
 package client
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/url"
-	"strings"
+	"net/http"
 )
 
 type Portfolio struct {
@@ -43,28 +42,27 @@ type PortfolioMetric struct {
 	Value    string `json:"value"`
 }
 
-func (c *Client) CreatePortfolio(portfolio *Portfolio) error {
-	params := url.Values{}
-	params.Set("key", portfolio.Key)
-	params.Set("name", portfolio.Name)
-	
-	if portfolio.Description != "" {
-		params.Set("description", portfolio.Description)
+func (c *Client) CreatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	if err := c.require(CapPortfolios); err != nil {
+		return err
 	}
 
-	resp, err := c.doRequest("POST", "portfolios/create", params)
-	if err != nil {
+	req := newRequest(http.MethodPost, "portfolios/create").
+		Set("key", portfolio.Key).
+		Set("name", portfolio.Name).
+		SetIfNotEmpty("description", portfolio.Description)
+
+	if err := c.call(ctx, req); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
 	// Configure selection mode and filters
-	if err := c.configurePortfolioSelection(portfolio.Key, &portfolio.Selection); err != nil {
+	if err := c.configurePortfolioSelection(ctx, portfolio.Key, &portfolio.Selection); err != nil {
 		return err
 	}
 
 	if portfolio.Selection.Mode == "FILTER" {
-		if err := c.configurePortfolioFilters(portfolio.Key, &portfolio.Filters); err != nil {
+		if err := c.configurePortfolioFilters(ctx, portfolio.Key, &portfolio.Filters); err != nil {
 			return err
 		}
 	}
@@ -72,28 +70,27 @@ func (c *Client) CreatePortfolio(portfolio *Portfolio) error {
 	return nil
 }
 
-func (c *Client) UpdatePortfolio(portfolio *Portfolio) error {
-	params := url.Values{}
-	params.Set("key", portfolio.Key)
-	params.Set("name", portfolio.Name)
-	
-	if portfolio.Description != "" {
-		params.Set("description", portfolio.Description)
+func (c *Client) UpdatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	if err := c.require(CapPortfolios); err != nil {
+		return err
 	}
 
-	resp, err := c.doRequest("POST", "portfolios/update", params)
-	if err != nil {
+	req := newRequest(http.MethodPost, "portfolios/update").
+		Set("key", portfolio.Key).
+		Set("name", portfolio.Name).
+		SetIfNotEmpty("description", portfolio.Description)
+
+	if err := c.call(ctx, req); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
 	// Update selection and filters
-	if err := c.configurePortfolioSelection(portfolio.Key, &portfolio.Selection); err != nil {
+	if err := c.configurePortfolioSelection(ctx, portfolio.Key, &portfolio.Selection); err != nil {
 		return err
 	}
 
 	if portfolio.Selection.Mode == "FILTER" {
-		if err := c.configurePortfolioFilters(portfolio.Key, &portfolio.Filters); err != nil {
+		if err := c.configurePortfolioFilters(ctx, portfolio.Key, &portfolio.Filters); err != nil {
 			return err
 		}
 	}
@@ -101,107 +98,69 @@ func (c *Client) UpdatePortfolio(portfolio *Portfolio) error {
 	return nil
 }
 
-func (c *Client) DeletePortfolio(key string) error {
-	params := url.Values{}
-	params.Set("key", key)
-
-	resp, err := c.doRequest("POST", "portfolios/delete", params)
-	if err != nil {
+func (c *Client) DeletePortfolio(ctx context.Context, key string) error {
+	if err := c.require(CapPortfolios); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	return nil
-}
+	req := newRequest(http.MethodPost, "portfolios/delete").
+		Set("key", key)
 
-func (c *Client) GetPortfolio(key string) (*Portfolio, error) {
-	params := url.Values{}
-	params.Set("key", key)
+	return c.call(ctx, req)
+}
 
-	resp, err := c.doRequest("GET", "portfolios/show", params)
-	if err != nil {
+func (c *Client) GetPortfolio(ctx context.Context, key string) (*Portfolio, error) {
+	if err := c.require(CapPortfolios); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var portfolio Portfolio
-	if err := json.NewDecoder(resp.Body).Decode(&portfolio); err != nil {
-		return nil, err
-	}
+	req := newRequest(http.MethodGet, "portfolios/show").
+		Set("key", key)
 
-	return &portfolio, nil
+	return doJSON[Portfolio](ctx, c, req)
 }
 
-func (c *Client) configurePortfolioSelection(key string, selection *PortfolioSelection) error {
-	params := url.Values{}
-	params.Set("key", key)
-	params.Set("mode", selection.Mode)
+func (c *Client) configurePortfolioSelection(ctx context.Context, key string, selection *PortfolioSelection) error {
+	req := newRequest(http.MethodPost, "portfolios/configure_selection").
+		Set("key", key).
+		Set("mode", selection.Mode)
 
 	switch selection.Mode {
 	case "MANUAL":
-		if len(selection.Projects) > 0 {
-			params.Set("projects", strings.Join(selection.Projects, ","))
-		}
+		req.SetList("projects", selection.Projects)
 	case "REGEXP":
-		if selection.ProjectPattern != "" {
-			params.Set("projectPattern", selection.ProjectPattern)
-		}
-		if selection.BranchPattern != "" {
-			params.Set("branchPattern", selection.BranchPattern)
-		}
+		req.SetIfNotEmpty("projectPattern", selection.ProjectPattern).
+			SetIfNotEmpty("branchPattern", selection.BranchPattern)
 	}
 
-	resp, err := c.doRequest("POST", "portfolios/configure_selection", params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	return nil
+	return c.call(ctx, req)
 }
 
-func (c *Client) configurePortfolioFilters(key string, filters *PortfolioFilters) error {
-	params := url.Values{}
-	params.Set("key", key)
-
-	if len(filters.Languages) > 0 {
-		params.Set("languages", strings.Join(filters.Languages, ","))
-	}
-	if len(filters.Tags) > 0 {
-		params.Set("tags", strings.Join(filters.Tags, ","))
-	}
-	if len(filters.QualityGates) > 0 {
-		params.Set("qualityGates", strings.Join(filters.QualityGates, ","))
-	}
+func (c *Client) configurePortfolioFilters(ctx context.Context, key string, filters *PortfolioFilters) error {
+	req := newRequest(http.MethodPost, "portfolios/configure_filters").
+		Set("key", key).
+		SetList("languages", filters.Languages).
+		SetList("tags", filters.Tags).
+		SetList("qualityGates", filters.QualityGates)
 
 	// Add compliance settings
-	if filters.Compliance.MinQualityGateStatus != "" {
-		params.Set("minQualityGateStatus", filters.Compliance.MinQualityGateStatus)
-	}
+	req.SetIfNotEmpty("minQualityGateStatus", filters.Compliance.MinQualityGateStatus)
 	if filters.Compliance.MinCoverage > 0 {
-		params.Set("minCoverage", fmt.Sprintf("%.2f", filters.Compliance.MinCoverage))
+		req.Set("minCoverage", fmt.Sprintf("%.2f", filters.Compliance.MinCoverage))
 	}
 	if filters.Compliance.MaxDuplications > 0 {
-		params.Set("maxDuplications", fmt.Sprintf("%.2f", filters.Compliance.MaxDuplications))
+		req.Set("maxDuplications", fmt.Sprintf("%.2f", filters.Compliance.MaxDuplications))
 	}
 	if filters.Compliance.MaxIssues > 0 {
-		params.Set("maxIssues", fmt.Sprintf("%d", filters.Compliance.MaxIssues))
-	}
-	if len(filters.Compliance.RequiredRules) > 0 {
-		params.Set("requiredRules", strings.Join(filters.Compliance.RequiredRules, ","))
+		req.Set("maxIssues", fmt.Sprintf("%d", filters.Compliance.MaxIssues))
 	}
+	req.SetList("requiredRules", filters.Compliance.RequiredRules)
 
 	// Add custom metrics
 	for metric, value := range filters.Metrics {
-		params.Set(fmt.Sprintf("metric_%s_operator", metric), value.Operator)
-		params.Set(fmt.Sprintf("metric_%s_value", metric), value.Value)
-	}
-
-	resp, err := c.doRequest("POST", "portfolios/configure_filters", params)
-	if err != nil {
-		return err
+		req.Set(fmt.Sprintf("metric_%s_operator", metric), value.Operator)
+		req.Set(fmt.Sprintf("metric_%s_value", metric), value.Value)
 	}
-	defer resp.Body.Close()
 
-	return nil
+	return c.call(ctx, req)
 }
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// rateRecoveryStep is the fraction of the configured rate restored after
+	// each successful response while the limiter is backed off
+	rateRecoveryStep = 0.1
+
+	// minRateFraction bounds how far the limiter backs off
+	minRateFraction = 0.05
+
+	// unlimitedThrottleRate stands in for the configured rate of a client
+	// without one once SonarQube starts throttling it: the limiter backs off
+	// from it, and lifts the limit again once it has recovered to it
+	unlimitedThrottleRate = rate.Limit(20)
+)
+
+// RateLimitConfig bounds how hard the client drives the SonarQube server. A
+// zero value for either limit disables it.
+type RateLimitConfig struct {
+	// MaxConcurrent is the maximum number of requests in flight at once
+	MaxConcurrent int
+
+	// RequestsPerSecond is the steady-state token bucket refill rate
+	RequestsPerSecond float64
+
+	// Burst is the token bucket size, defaulting to 1
+	Burst int
+}
+
+// WithMaxConcurrency caps the number of requests in flight at once
+func WithMaxConcurrency(n int) ClientOption {
+	return func(c *Client) {
+		c.rateLimit.MaxConcurrent = n
+	}
+}
+
+// WithRateLimit enables a token bucket request rate that backs off
+// automatically on 429/503 responses
+func WithRateLimit(requestsPerSecond float64, burst int) ClientOption {
+	return func(c *Client) {
+		c.rateLimit.RequestsPerSecond = requestsPerSecond
+		c.rateLimit.Burst = burst
+	}
+}
+
+// adaptiveLimiter combines a concurrency semaphore with a token bucket whose
+// rate is halved on throttling responses and recovers gradually on success.
+// Without a configured rate the bucket is unlimited until the first
+// throttling response. A Retry-After header pauses all requests until the
+// given time.
+type adaptiveLimiter struct {
+	sem     chan struct{}
+	limiter *rate.Limiter
+	maxRate rate.Limit
+	logger  *logrus.Logger
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func newAdaptiveLimiter(cfg RateLimitConfig, logger *logrus.Logger) *adaptiveLimiter {
+	l := &adaptiveLimiter{
+		maxRate: rate.Inf,
+		logger:  logger,
+	}
+
+	if cfg.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	if cfg.RequestsPerSecond > 0 {
+		l.maxRate = rate.Limit(cfg.RequestsPerSecond)
+	}
+	l.limiter = rate.NewLimiter(l.maxRate, burst)
+
+	return l
+}
+
+// acquire blocks until a request may be sent and returns a release func
+func (l *adaptiveLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if err := l.waitPause(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.limiter.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (l *adaptiveLimiter) waitPause(ctx context.Context) error {
+	l.mu.Lock()
+	wait := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// observe adjusts the rate based on a response
+func (l *adaptiveLimiter) observe(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if retryAfter, ok := parseRetryAfter(resp); ok {
+		if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+			l.logger.WithField("retry_after", retryAfter).Warn("SonarQube requested a pause, delaying requests")
+		}
+	}
+
+	ceiling := l.maxRate
+	if ceiling == rate.Inf {
+		ceiling = unlimitedThrottleRate
+	}
+
+	current := l.limiter.Limit()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		next := current / 2
+		if current == rate.Inf {
+			next = ceiling / 2
+		}
+		if floor := ceiling * minRateFraction; next < floor {
+			next = floor
+		}
+		if next != current {
+			l.limiter.SetLimit(next)
+			l.logger.WithFields(logrus.Fields{
+				"status": resp.StatusCode,
+				"rate":   float64(next),
+			}).Warn("SonarQube is throttling, reducing request rate")
+		}
+	case resp.StatusCode < 400 && current < l.maxRate:
+		next := current + ceiling*rateRecoveryStep
+		if next >= ceiling {
+			next = l.maxRate
+		}
+		l.limiter.SetLimit(next)
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// limitedTransport applies an adaptiveLimiter to every attempt, including
+// retries issued by retryablehttp
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *adaptiveLimiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := t.base.RoundTrip(req)
+	t.limiter.observe(resp)
+	return resp, err
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newLimitedTestClient(cfg RateLimitConfig) (*http.Client, *adaptiveLimiter) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := newAdaptiveLimiter(cfg, logger)
+	return &http.Client{Transport: &limitedTransport{base: http.DefaultTransport, limiter: limiter}}, limiter
+}
+
+func getStatus(t *testing.T, c *http.Client, url string) int {
+	t.Helper()
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRateLimitCapsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c, _ := newLimitedTestClient(RateLimitConfig{MaxConcurrent: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, getStatus(t, c, srv.URL))
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&peak))
+}
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c, _ := newLimitedTestClient(RateLimitConfig{RequestsPerSecond: 20, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		getStatus(t, c, srv.URL)
+	}
+
+	// The first request uses the burst, the other five wait 50ms each
+	assert.GreaterOrEqual(t, time.Since(start), 240*time.Millisecond)
+}
+
+func TestRateLimitBacksOffAndRecovers(t *testing.T) {
+	for name, cfg := range map[string]RateLimitConfig{
+		"configured rate": {RequestsPerSecond: 40, Burst: 1},
+		"unlimited":       {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var throttle atomic.Value
+			throttle.Store(0)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if status := throttle.Load().(int); status != 0 {
+					w.WriteHeader(status)
+				}
+			}))
+			defer srv.Close()
+
+			c, limiter := newLimitedTestClient(cfg)
+			configured := limiter.limiter.Limit()
+
+			throttle.Store(http.StatusTooManyRequests)
+			assert.Equal(t, http.StatusTooManyRequests, getStatus(t, c, srv.URL))
+			slowed := limiter.limiter.Limit()
+			assert.Less(t, float64(slowed), float64(configured), "a 429 halves the rate")
+
+			throttle.Store(http.StatusServiceUnavailable)
+			assert.Equal(t, http.StatusServiceUnavailable, getStatus(t, c, srv.URL))
+			assert.Equal(t, slowed/2, limiter.limiter.Limit(), "a 503 halves it again")
+
+			throttle.Store(0)
+			for i := 0; i < 20 && limiter.limiter.Limit() != configured; i++ {
+				getStatus(t, c, srv.URL)
+			}
+			assert.Equal(t, configured, limiter.limiter.Limit(), "successes restore the configured rate")
+		})
+	}
+}
+
+func TestRateLimitHonoursRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	c, limiter := newLimitedTestClient(RateLimitConfig{})
+	assert.Equal(t, http.StatusTooManyRequests, getStatus(t, c, srv.URL))
+	assert.Less(t, limiter.limiter.Limit(), rate.Inf, "an unlimited client backs off too")
+
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, getStatus(t, c, srv.URL))
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond, "the next request waits for Retry-After")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limiter.mu.Lock()
+	limiter.pausedUntil = time.Now().Add(time.Minute)
+	limiter.mu.Unlock()
+	_, err := limiter.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "a pause gives up with the context")
+}
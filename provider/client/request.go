@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-retryablehttp"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Request describes a single SonarQube Web API call. Parameters are sent as
+// a query string for GET requests and as a form-encoded body for POSTs.
+// A {name} segment in Path is filled in from the parameter of that name.
+type Request struct {
+	Method string
+	Path   string
+	Params url.Values
+
+	// JSON sends the parameters of a POST, PATCH or PUT as a flat JSON
+	// object of strings, as the v2 API expects, instead of a form
+	JSON bool
+}
+
+func newRequest(method, path string) *Request {
+	return &Request{
+		Method: method,
+		Path:   path,
+		Params: url.Values{},
+	}
+}
+
+// Set sets a parameter, replacing any existing values
+func (r *Request) Set(key, value string) *Request {
+	r.Params.Set(key, value)
+	return r
+}
+
+// SetIfNotEmpty sets a parameter only when value is non-empty
+func (r *Request) SetIfNotEmpty(key, value string) *Request {
+	if value != "" {
+		r.Params.Set(key, value)
+	}
+	return r
+}
+
+// Add appends values to a repeated parameter, e.g. values=a&values=b for
+// multi-value settings
+func (r *Request) Add(key string, values ...string) *Request {
+	for _, v := range values {
+		r.Params.Add(key, v)
+	}
+	return r
+}
+
+// AsJSON sends the parameters as a JSON body, see Request.JSON
+func (r *Request) AsJSON() *Request {
+	r.JSON = true
+	return r
+}
+
+// SetList sets a comma-separated list parameter, e.g. tags=a,b. Empty lists
+// are skipped.
+func (r *Request) SetList(key string, values []string) *Request {
+	if len(values) > 0 {
+		r.Params.Set(key, strings.Join(values, ","))
+	}
+	return r
+}
+
+// apiURL joins an API path onto the server's base URL, keeping a context
+// path such as https://example.com/sonarqube and ignoring trailing slashes
+func apiURL(baseURL, path string) (*url.URL, error) {
+	base, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SonarQube URL %q: %w", baseURL, err)
+	}
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid SonarQube URL %q: must be an absolute http or https URL", baseURL)
+	}
+
+	endpoint := base.JoinPath("api", path)
+	endpoint.RawQuery, endpoint.Fragment = "", ""
+	return endpoint, nil
+}
+
+// expandPath fills the {name} segments of r.Path and returns the
+// parameters left to send
+func (r *Request) expandPath() (string, url.Values) {
+	if !strings.Contains(r.Path, "{") {
+		return r.Path, r.Params
+	}
+
+	params := make(url.Values, len(r.Params))
+	for name, v := range r.Params {
+		params[name] = v
+	}
+	segments := strings.Split(r.Path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := segment[1 : len(segment)-1]
+			segments[i] = params.Get(name)
+			delete(params, name)
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// build encodes the request against the given base URL
+func (r *Request) build(ctx context.Context, baseURL string) (*retryablehttp.Request, error) {
+	path, params := r.expandPath()
+	endpoint, err := apiURL(baseURL, path)
+	if err != nil {
+		return nil, err
+	}
+	encoded := params.Encode()
+
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodDelete:
+		endpoint.RawQuery = encoded
+		return retryablehttp.NewRequestWithContext(ctx, r.Method, endpoint.String(), nil)
+	case r.JSON:
+		body := make(map[string]string, len(params))
+		for name := range params {
+			body[name] = params.Get(name)
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		req, err := retryablehttp.NewRequestWithContext(ctx, r.Method, endpoint.String(), data)
+		if err != nil {
+			return nil, err
+		}
+		// PATCH bodies are JSON merge patches: fields left out are unchanged
+		if r.Method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	default:
+		req, err := retryablehttp.NewRequestWithContext(ctx, r.Method, endpoint.String(), []byte(encoded))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+}
+
+// call executes req and discards the response body
+func (c *Client) call(ctx context.Context, req *Request) error {
+	resp, err := c.doRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// doJSON executes req and decodes the JSON response into a new T
+func doJSON[T any](ctx context.Context, c *Client, req *Request) (*T, error) {
+	resp, err := c.doRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out T
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode %s response: %w", req.Path, err)
+	}
+	return &out, nil
+}
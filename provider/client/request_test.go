@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestRequestBuildGETUsesQueryString(t *testing.T) {
+	r := newRequest(http.MethodGet, "settings/values").
+		Set("component", "my-project").
+		SetList("keys", []string{"a", "b"})
+
+	req, err := r.build(context.Background(), "https://sonar.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://sonar.example.com/api/settings/values?component=my-project&keys=a%2Cb", req.URL.String())
+	assert.Empty(t, req.Header.Get("Content-Type"))
+}
+
+func TestRequestBuildPOSTUsesFormBody(t *testing.T) {
+	r := newRequest(http.MethodPost, "settings/set").
+		Set("key", "sonar.exclusions").
+		Add("values", "**/vendor/**", "**/gen/**").
+		SetIfNotEmpty("component", "")
+
+	req, err := r.build(context.Background(), "https://sonar.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://sonar.example.com/api/settings/set", req.URL.String())
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+
+	body, err := req.BodyBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "key=sonar.exclusions&values=%2A%2A%2Fvendor%2F%2A%2A&values=%2A%2A%2Fgen%2F%2A%2A", string(body))
+}
+
+func TestRequestBuildJoinsContextPath(t *testing.T) {
+	for _, base := range []string{
+		"https://example.com/sonarqube",
+		"https://example.com/sonarqube/",
+		" https://example.com//sonarqube// ",
+	} {
+		req, err := newRequest(http.MethodGet, "server/version").build(context.Background(), base)
+		require.NoError(t, err, base)
+		assert.Equal(t, "https://example.com/sonarqube/api/server/version", req.URL.String(), base)
+	}
+
+	_, err := newRequest(http.MethodGet, "server/version").build(context.Background(), "sonar.example.com")
+	assert.ErrorContains(t, err, "must be an absolute http or https URL")
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// statusProbeTimeout bounds the api/system/status call made when deciding
+// whether a 503 is caused by the server still starting up
+const statusProbeTimeout = 5 * time.Second
+
+// startingStatuses are api/system/status values during which SonarQube
+// answers 503 but will become available without intervention
+var startingStatuses = map[string]bool{
+	"STARTING":              true,
+	"RESTARTING":            true,
+	"DB_MIGRATION_NEEDED":   true,
+	"DB_MIGRATION_RUNNING":  true,
+	"DB_MIGRATION_REQUIRED": true,
+}
+
+// indexingMessages are fragments of the 400 errors SonarQube returns while
+// Elasticsearch is still being initialized or reindexed after startup
+var indexingMessages = []string{
+	"elasticsearch",
+	"indexation is in progress",
+	"index is being",
+	"indices are being",
+	"not yet indexed",
+}
+
+type methodKey struct{}
+
+// withMethod records the HTTP method on the request context so the retry
+// policy can tell idempotent requests apart when only an error is available
+func withMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+func methodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodKey{}).(string)
+	return method
+}
+
+// retryPolicy is the default CheckRetry for the client. It retries:
+//   - any request that never reached the server (connection refused, DNS)
+//   - 429 responses, and 503 responses carrying Retry-After
+//   - 503 responses while the server reports a starting or migration status
+//   - 400 responses caused by Elasticsearch still indexing after startup
+//   - connection errors and other 5xx responses for idempotent requests
+//
+// A POST that reached the server and may have been applied is never retried.
+// The returned error is always nil so the final response reaches doRequest
+// and is reported as an APIError.
+func (c *Client) retryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	retry, reason := c.shouldRetry(ctx, resp, err)
+
+	fields := logrus.Fields{
+		"method": methodFromContext(ctx),
+		"retry":  retry,
+		"reason": reason,
+	}
+	if resp != nil {
+		fields["status"] = resp.StatusCode
+		if resp.Request != nil {
+			fields["path"] = resp.Request.URL.Path
+		}
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	if retry {
+		c.logger.WithFields(fields).Info("Retrying SonarQube request")
+	} else if err != nil || (resp != nil && resp.StatusCode >= 400) {
+		c.logger.WithFields(fields).Debug("Not retrying SonarQube request")
+	}
+
+	return retry, nil
+}
+
+func (c *Client) shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, string) {
+	idempotent := methodFromContext(ctx) != http.MethodPost
+
+	if err != nil {
+		if isPermanentTransportError(err) {
+			return false, "permanent transport error"
+		}
+		if neverSent(err) {
+			return true, "connection failed before the request was sent"
+		}
+		if idempotent {
+			return true, "transport error on idempotent request"
+		}
+		return false, "transport error after a POST may have reached the server"
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, "rate limited"
+
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		if _, ok := parseRetryAfter(resp); ok {
+			return true, "service unavailable with Retry-After"
+		}
+		if status := c.probeStatus(ctx); startingStatuses[status] {
+			return true, "server status " + status
+		}
+		if idempotent {
+			return true, "service unavailable"
+		}
+		return false, "service unavailable for POST"
+
+	case resp.StatusCode == http.StatusBadRequest:
+		if isIndexingError(resp) {
+			return true, "search index not ready"
+		}
+		return false, "bad request"
+
+	case resp.StatusCode == 0 || (resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented):
+		if idempotent {
+			return true, "server error"
+		}
+		return false, "server error after a POST reached the server"
+	}
+
+	return false, "success or client error"
+}
+
+// neverSent reports whether err happened while connecting, so the request
+// cannot have been processed by the server
+func neverSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+func isPermanentTransportError(err error) bool {
+	if errors.Is(err, ErrNoRecordedInteraction) {
+		return true
+	}
+	var oauth2Err *OAuth2Error
+	if errors.As(err, &oauth2Err) {
+		return oauth2Err.StatusCode < 500
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalid x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalid) {
+		return true
+	}
+	// Handshake failures such as a rejected client certificate or no
+	// common protocol version come from configuration, not the network
+	msg := err.Error()
+	return strings.Contains(msg, "stopped after") && strings.Contains(msg, "redirects") ||
+		strings.Contains(msg, "unsupported protocol scheme") ||
+		strings.Contains(msg, "tls: ")
+}
+
+// isIndexingError inspects a 400 response body for SonarQube's search index
+// errors. The body is restored so it can be read again.
+func isIndexingError(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	for _, msg := range newAPIError("", "", resp.StatusCode, body).Messages {
+		lower := strings.ToLower(msg)
+		for _, fragment := range indexingMessages {
+			if strings.Contains(lower, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probeStatus returns the status reported by api/system/status, or "" if it
+// cannot be determined
+func (c *Client) probeStatus(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, statusProbeTimeout)
+	defer cancel()
+
+	endpoint, err := apiURL(c.host, "system/status")
+	if err != nil {
+		return ""
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return ""
+	}
+	c.setExtraHeaders(req.Header)
+
+	resp, err := c.client.HTTPClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return ""
+	}
+	return status.Status
+}
+
+// retryBackoff honours Retry-After given in seconds or as an HTTP date, and
+// otherwise falls back to exponential backoff
+func retryBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp); ok {
+			return wait
+		}
+	}
+	return retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+}
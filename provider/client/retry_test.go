@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryTestClient(url string) *Client {
+	return NewClient(url, "token", WithRetryConfig(RetryConfig{
+		MaxRetries: 3,
+		WaitMin:    time.Millisecond,
+		WaitMax:    5 * time.Millisecond,
+	}))
+}
+
+func TestRetryPolicyRetriesWhileServerStarting(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/system/status" {
+			_, _ = w.Write([]byte(`{"status":"STARTING"}`))
+			return
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"project":{"key":"demo"}}`))
+	}))
+	defer srv.Close()
+
+	project, err := newRetryTestClient(srv.URL).CreateProject(context.Background(), "Demo", "demo", "private", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "demo", project.Key)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyRetriesIndexingErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":[{"msg":"Elasticsearch indices are being initialized"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"paging":{"pageIndex":1,"pageSize":500,"total":0},"users":[]}`))
+	}))
+	defer srv.Close()
+
+	_, err := newRetryTestClient(srv.URL).SearchUsers(context.Background(), "").All()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyDoesNotRetryPOSTServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errors":[{"msg":"boom"}]}`))
+	}))
+	defer srv.Close()
+
+	err := newRetryTestClient(srv.URL).DeleteProject(context.Background(), "demo")
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, []string{"boom"}, apiErr.Messages)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+func TestCallTimeoutStopsRetryWaits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token", WithCallTimeout(50*time.Millisecond), WithRetryConfig(RetryConfig{
+		MaxRetries: 5,
+		WaitMin:    time.Second,
+		WaitMax:    time.Second,
+	}))
+
+	start := time.Now()
+	_, err := c.ReadProject(context.Background(), "demo")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestCanceledContextStopsRetryWaits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	c := NewClient(srv.URL, "token", WithRetryConfig(RetryConfig{
+		MaxRetries: 5,
+		WaitMin:    time.Second,
+		WaitMax:    time.Second,
+	}))
+
+	start := time.Now()
+	_, err := c.ReadProject(ctx, "demo")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Rule represents a SonarQube coding rule
+type Rule struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"htmlDesc,omitempty"`
+	Severity    string `json:"severity"`
+	Status      string `json:"status"`
+	Template    bool   `json:"isTemplate"`
+	Language    string `json:"lang"`
+	Type        string `json:"type"`
+}
+
+// QualityProfile represents a SonarQube quality profile
+type QualityProfile struct {
+	Key             string `json:"key"`
+	Name            string `json:"name"`
+	Language        string `json:"language"`
+	LanguageName    string `json:"languageName"`
+	IsDefault       bool   `json:"isDefault"`
+	IsBuiltIn       bool   `json:"isBuiltIn"`
+	ActiveRuleCount int    `json:"activeRuleCount"`
+}
+
+// Language represents a language supported by the installed analyzers
+type Language struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// SearchRules streams every rule matching query, or all rules when query is
+// empty. Instances with more than 10,000 rules are walked one language at a
+// time to stay under SonarQube's result cap.
+func (c *Client) SearchRules(ctx context.Context, query string) *Iterator[Rule] {
+	req := newRequest(http.MethodGet, "rules/search").
+		SetIfNotEmpty("q", query)
+
+	return newIterator[Rule](ctx, c, req, "rules").
+		WithNarrowing(func(r Rule) string { return r.Key }, c.languageFilters)
+}
+
+// GetRule looks up a single rule by key, e.g. "java:S1481"
+func (c *Client) GetRule(ctx context.Context, key string) (*Rule, error) {
+	req := newRequest(http.MethodGet, "rules/show").
+		Set("key", key)
+
+	result, err := doJSON[struct {
+		Rule Rule `json:"rule"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.Rule, nil
+}
+
+// SearchQualityProfiles streams the quality profiles for language, or for
+// all languages when language is empty
+func (c *Client) SearchQualityProfiles(ctx context.Context, language string) *Iterator[QualityProfile] {
+	req := newRequest(http.MethodGet, "qualityprofiles/search").
+		SetIfNotEmpty("language", language)
+
+	return newIterator[QualityProfile](ctx, c, req, "profiles")
+}
+
+// ListLanguages returns every language supported by the installed analyzers
+func (c *Client) ListLanguages(ctx context.Context) ([]Language, error) {
+	return newIterator[Language](ctx, c, newRequest(http.MethodGet, "languages/list"), "languages").All()
+}
+
+// GetLanguage looks up a single installed language by key
+func (c *Client) GetLanguage(ctx context.Context, key string) (*Language, error) {
+	languages, err := c.ListLanguages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lang := range languages {
+		if lang.Key == key {
+			return &lang, nil
+		}
+	}
+
+	return nil, newNotFoundError("languages/list", "language not found: %s", key)
+}
+
+// languageFilters returns one languages= filter per installed language
+func (c *Client) languageFilters(ctx context.Context) ([]url.Values, error) {
+	languages, err := c.ListLanguages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make([]url.Values, len(languages))
+	for i, lang := range languages {
+		filters[i] = url.Values{"languages": {lang.Key}}
+	}
+	return filters, nil
+}
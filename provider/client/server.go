@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Edition is the SonarQube product edition
+type Edition string
+
+const (
+	EditionCommunity  Edition = "community"
+	EditionDeveloper  Edition = "developer"
+	EditionEnterprise Edition = "enterprise"
+	EditionDataCenter Edition = "datacenter"
+)
+
+// Version is a parsed SonarQube server version such as 9.9.1.69595
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+// ParseVersion parses the dotted version returned by api/server/version
+func ParseVersion(raw string) (Version, error) {
+	v := Version{Raw: strings.TrimSpace(raw)}
+	parts := strings.Split(v.Raw, ".")
+	if len(parts) < 2 {
+		return v, fmt.Errorf("invalid SonarQube version %q", raw)
+	}
+
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i := 0; i < len(fields) && i < len(parts); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return v, fmt.Errorf("invalid SonarQube version %q: %w", raw, err)
+		}
+		*fields[i] = n
+	}
+
+	return v, nil
+}
+
+// AtLeast reports whether v is major.minor or newer
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+func (v Version) String() string {
+	if v.Raw != "" {
+		return v.Raw
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// ServerInfo describes the SonarQube server the client is talking to
+type ServerInfo struct {
+	Version Version
+	Edition Edition
+}
+
+// Capability names a server feature whose availability or API shape depends
+// on the server version or edition
+type Capability string
+
+const (
+	// CapQualityGateByName addresses quality gates by name (gateName, name,
+	// currentName) instead of the numeric ids removed in 10.0
+	CapQualityGateByName Capability = "quality_gate_by_name"
+
+	// CapPortfolios is the portfolios API, Enterprise Edition and above
+	CapPortfolios Capability = "portfolios"
+
+	// CapApplications is the applications API, Developer Edition and above
+	CapApplications Capability = "applications"
+
+	// CapGroupsV2 is the v2 authorizations/groups API, which replaces the
+	// deprecated api/user_groups mutations
+	CapGroupsV2 Capability = "groups_v2"
+)
+
+type capabilityRequirement struct {
+	major, minor int
+	editions     []Edition
+}
+
+// capabilities lists the minimum version and, where relevant, the editions
+// that provide each capability
+var capabilities = map[Capability]capabilityRequirement{
+	CapQualityGateByName: {major: 8, minor: 4},
+	CapPortfolios:        {editions: []Edition{EditionEnterprise, EditionDataCenter}},
+	CapApplications:      {editions: []Edition{EditionDeveloper, EditionEnterprise, EditionDataCenter}},
+	CapGroupsV2:          {major: 10, minor: 5},
+}
+
+// Supports reports whether the server provides the capability
+func (s *ServerInfo) Supports(capability Capability) bool {
+	req, ok := capabilities[capability]
+	if !ok {
+		return false
+	}
+
+	if !s.Version.AtLeast(req.major, req.minor) {
+		return false
+	}
+
+	if len(req.editions) == 0 {
+		return true
+	}
+	for _, e := range req.editions {
+		if s.Edition == e {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsupportedError is returned when an API method needs a capability the
+// connected server does not have
+type UnsupportedError struct {
+	Capability Capability
+	Server     ServerInfo
+}
+
+func (e *UnsupportedError) Error() string {
+	req := capabilities[e.Capability]
+	if len(req.editions) > 0 {
+		editions := make([]string, len(req.editions))
+		for i, ed := range req.editions {
+			editions[i] = string(ed)
+		}
+		return fmt.Sprintf("%s requires SonarQube %s edition, but the server is %s edition %s",
+			e.Capability, strings.Join(editions, "/"), e.Server.Edition, e.Server.Version)
+	}
+	return fmt.Sprintf("%s requires SonarQube %d.%d or later, but the server is %s",
+		e.Capability, req.major, req.minor, e.Server.Version)
+}
+
+// DetectServer queries api/server/version and api/navigation/global and
+// records the result for later capability checks
+func (c *Client) DetectServer(ctx context.Context) (*ServerInfo, error) {
+	resp, err := c.doRequest(ctx, newRequest(http.MethodGet, "server/version"))
+	if IsUnauthorized(err) && c.server == nil && !c.authFallback && (c.authMode == AuthAuto || c.authMode == "") {
+		// Servers older than 10.0 don't know bearer tokens, and with
+		// sonar.forceAuthentication even api/server/version rejects them
+		c.authFallback = true
+		resp, err = c.doRequest(ctx, newRequest(http.MethodGet, "server/version"))
+		if err != nil {
+			c.authFallback = false
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect SonarQube version: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SonarQube version: %w", err)
+	}
+
+	version, err := ParseVersion(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	global, err := doJSON[struct {
+		Edition string `json:"edition"`
+	}](ctx, c, newRequest(http.MethodGet, "navigation/global"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect SonarQube edition: %w", err)
+	}
+
+	edition := Edition(strings.ToLower(global.Edition))
+	if edition == "" {
+		edition = EditionCommunity
+	}
+
+	c.server = &ServerInfo{Version: version, Edition: edition}
+	c.logger.WithField("version", version.String()).WithField("edition", edition).Debug("Detected SonarQube server")
+
+	return c.server, nil
+}
+
+// Server returns the detected server, or nil if DetectServer has not run
+func (c *Client) Server() *ServerInfo {
+	return c.server
+}
+
+// supports reports whether the detected server has the capability. When the
+// server has not been detected the newest API shape is assumed.
+func (c *Client) supports(capability Capability) bool {
+	return c.server == nil || c.server.Supports(capability)
+}
+
+// require returns an UnsupportedError if the detected server lacks the
+// capability
+func (c *Client) require(capability Capability) error {
+	if c.supports(capability) {
+		return nil
+	}
+	return &UnsupportedError{Capability: capability, Server: *c.server}
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+)
+
+// The provider's resources and data sources depend on these interfaces
+// rather than on *Client, so tests can inject a mock or a partial
+// implementation as provider meta. *Client implements all of them.
+
+// Projects manages projects
+type Projects interface {
+	CreateProject(ctx context.Context, name, key, visibility string, mainBranch string, tags []string) (*Project, error)
+	ReadProject(ctx context.Context, key string) (*Project, error)
+	SearchProjects(ctx context.Context, query string) *Iterator[Project]
+	UpdateProject(ctx context.Context, key string, name string, visibility string, tags []string) (*Project, error)
+	DeleteProject(ctx context.Context, key string) error
+	SearchProjectsByFilter(ctx context.Context, filter ProjectFilter) *Iterator[Project]
+	BulkDeleteProjects(ctx context.Context, filter ProjectFilter) error
+	BulkUpdateProjectKey(ctx context.Context, project, from, to string, dryRun bool) ([]KeyUpdate, error)
+}
+
+// QualityGates manages quality gates and their conditions
+type QualityGates interface {
+	CreateQualityGate(ctx context.Context, name string) (*QualityGate, error)
+	ReadQualityGate(ctx context.Context, gate string) (*QualityGate, error)
+	GetQualityGateByName(ctx context.Context, name string) (*QualityGate, error)
+	UpdateQualityGate(ctx context.Context, gate, name string) (*QualityGate, error)
+	DeleteQualityGate(ctx context.Context, gate string) error
+	CreateQualityGateCondition(ctx context.Context, gate, metric, op, error string) (*Condition, error)
+	DeleteQualityGateCondition(ctx context.Context, id string) error
+}
+
+// Portfolios manages Enterprise Edition portfolios
+type Portfolios interface {
+	CreatePortfolio(ctx context.Context, portfolio *Portfolio) error
+	GetPortfolio(ctx context.Context, key string) (*Portfolio, error)
+	UpdatePortfolio(ctx context.Context, portfolio *Portfolio) error
+	DeletePortfolio(ctx context.Context, key string) error
+}
+
+// ComputeEngine follows the background tasks SonarQube runs after some
+// changes
+type ComputeEngine interface {
+	GetTask(ctx context.Context, id string) (*Task, error)
+	WaitForTask(ctx context.Context, id string) (*Task, error)
+	WaitForComponentQueue(ctx context.Context, component string) (*Task, error)
+}
+
+// Users manages user accounts
+type Users interface {
+	SearchUsers(ctx context.Context, query string) *Iterator[User]
+	GetUser(ctx context.Context, login string) (*User, error)
+	GetDeactivatedUser(ctx context.Context, login string) (*User, error)
+	CreateUser(ctx context.Context, user *User, password string) (*User, error)
+	UpdateUser(ctx context.Context, login, name, email string, scmAccounts []string) (*User, error)
+	UpdateUserLogin(ctx context.Context, login, newLogin string) error
+	ChangeUserPassword(ctx context.Context, login, password string) error
+	DeactivateUser(ctx context.Context, login string) error
+}
+
+// Groups manages user groups and their members
+type Groups interface {
+	SearchGroups(ctx context.Context, query string) *Iterator[Group]
+	GetGroup(ctx context.Context, name string) (*Group, error)
+	CreateGroup(ctx context.Context, name, description string) (*Group, error)
+	UpdateGroup(ctx context.Context, currentName, name, description string) (*Group, error)
+	DeleteGroup(ctx context.Context, name string) error
+	SearchGroupMembers(ctx context.Context, group, query string) *Iterator[GroupMember]
+	AddGroupMember(ctx context.Context, group, login string) error
+	RemoveGroupMember(ctx context.Context, group, login string) error
+}
+
+// Permissions grants and revokes global and project permissions
+type Permissions interface {
+	AddUserPermission(ctx context.Context, project, login, permission string) error
+	RemoveUserPermission(ctx context.Context, project, login, permission string) error
+	AddGroupPermission(ctx context.Context, project, group, permission string) error
+	RemoveGroupPermission(ctx context.Context, project, group, permission string) error
+	SearchUserPermissions(ctx context.Context, project string) *Iterator[UserPermissions]
+	SearchGroupPermissions(ctx context.Context, project string) *Iterator[GroupPermissions]
+}
+
+// Settings reads and writes global and component settings
+type Settings interface {
+	GetSettings(ctx context.Context, component string, keys ...string) ([]Setting, error)
+	SetSetting(ctx context.Context, component, key, value string) error
+	SetSettingValues(ctx context.Context, component, key string, values []string) error
+	ResetSettings(ctx context.Context, component string, keys ...string) error
+}
+
+// Catalog looks up the metrics, rules, quality profiles and languages
+// installed on the server
+type Catalog interface {
+	SearchMetrics(ctx context.Context) *Iterator[Metric]
+	GetMetric(ctx context.Context, key string) (*Metric, error)
+	SearchRules(ctx context.Context, query string) *Iterator[Rule]
+	GetRule(ctx context.Context, key string) (*Rule, error)
+	SearchQualityProfiles(ctx context.Context, language string) *Iterator[QualityProfile]
+	ListLanguages(ctx context.Context) ([]Language, error)
+	GetLanguage(ctx context.Context, key string) (*Language, error)
+}
+
+// API is the whole SonarQube Web API surface used by the provider
+type API interface {
+	Projects
+	QualityGates
+	Portfolios
+	Users
+	Groups
+	Permissions
+	Settings
+	Catalog
+	ComputeEngine
+
+	// Server returns the detected server, or nil if it is unknown
+	Server() *ServerInfo
+}
+
+var _ API = (*Client)(nil)
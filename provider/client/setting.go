@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Setting is a global or component setting. Multi-value settings are
+// returned in Values rather than Value.
+type Setting struct {
+	Key       string   `json:"key"`
+	Value     string   `json:"value,omitempty"`
+	Values    []string `json:"values,omitempty"`
+	Inherited bool     `json:"inherited,omitempty"`
+}
+
+// GetSettings returns the settings on component, or the global settings
+// when component is empty. With no keys every set value is returned.
+func (c *Client) GetSettings(ctx context.Context, component string, keys ...string) ([]Setting, error) {
+	req := newRequest(http.MethodGet, "settings/values").
+		SetIfNotEmpty("component", component).
+		SetList("keys", keys)
+
+	result, err := doJSON[struct {
+		Settings []Setting `json:"settings"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Settings, nil
+}
+
+// SetSetting sets a single-value key on component, or globally when
+// component is empty
+func (c *Client) SetSetting(ctx context.Context, component, key, value string) error {
+	req := newRequest(http.MethodPost, "settings/set").
+		SetIfNotEmpty("component", component).
+		Set("key", key).
+		Set("value", value)
+
+	return c.call(ctx, req)
+}
+
+// SetSettingValues sets a multi-value key on component, or globally when
+// component is empty
+func (c *Client) SetSettingValues(ctx context.Context, component, key string, values []string) error {
+	req := newRequest(http.MethodPost, "settings/set").
+		SetIfNotEmpty("component", component).
+		Set("key", key).
+		Add("values", values...)
+
+	return c.call(ctx, req)
+}
+
+// ResetSettings restores keys on component, or globally when component is
+// empty, to their defaults
+func (c *Client) ResetSettings(ctx context.Context, component string, keys ...string) error {
+	req := newRequest(http.MethodPost, "settings/reset").
+		SetIfNotEmpty("component", component).
+		SetList("keys", keys)
+
+	return c.call(ctx, req)
+}
@@ -1,11 +1,8 @@
 package client
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/url"
-	"strings"
+	"context"
+	"net/http"
 )
 
 // Project represents a SonarQube project
@@ -17,6 +14,9 @@ type Project struct {
 	Tags        []string `json:"tags"`
 	Qualifier   string   `json:"qualifier"`
 	Description string   `json:"description,omitempty"`
+
+	// LastAnalysisDate is empty for provisioned projects never analyzed
+	LastAnalysisDate string `json:"lastAnalysisDate,omitempty"`
 }
 
 // QualityGate represents a SonarQube quality gate
@@ -36,187 +36,169 @@ type Condition struct {
 }
 
 // Project API Methods
-func (c *Client) CreateProject(name, key, visibility string, mainBranch string, tags []string) (*Project, error) {
-	params := url.Values{}
-	params.Set("name", name)
-	params.Set("project", key)
-	params.Set("visibility", visibility)
-	
-	if mainBranch != "" {
-		params.Set("mainBranch", mainBranch)
-	}
-	
-	if len(tags) > 0 {
-		params.Set("tags", strings.Join(tags, ","))
-	}
-
-	resp, err := c.doRequest("POST", "projects/create", params)
+func (c *Client) CreateProject(ctx context.Context, name, key, visibility string, mainBranch string, tags []string) (*Project, error) {
+	req := newRequest(http.MethodPost, "projects/create").
+		Set("name", name).
+		Set("project", key).
+		Set("visibility", visibility).
+		SetIfNotEmpty("mainBranch", mainBranch).
+		SetList("tags", tags)
+
+	result, err := doJSON[struct {
+		Project Project `json:"project"`
+	}](ctx, c, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var project Project
-	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
-		return nil, err
-	}
-
-	return &project, nil
+	return &result.Project, nil
 }
 
-func (c *Client) ReadProject(key string) (*Project, error) {
-	params := url.Values{}
-	params.Set("project", key)
-
-	resp, err := c.doRequest("GET", "projects/search", params)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (c *Client) ReadProject(ctx context.Context, key string) (*Project, error) {
+	it := newIterator[Project](ctx, c, newRequest(http.MethodGet, "projects/search").
+		Set("projects", key), "components")
 
-	var result struct {
-		Components []Project `json:"components"`
+	for it.Next() {
+		if project := it.Item(); project.Key == key {
+			return &project, nil
+		}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := it.Err(); err != nil {
 		return nil, err
 	}
 
-	if len(result.Components) == 0 {
-		return nil, fmt.Errorf("project not found: %s", key)
-	}
-
-	return &result.Components[0], nil
+	return nil, newNotFoundError("projects/search", "project not found: %s", key)
 }
 
-func (c *Client) UpdateProject(key string, name string, visibility string, tags []string) (*Project, error) {
-	params := url.Values{}
-	params.Set("project", key)
-	
-	if name != "" {
-		params.Set("name", name)
-	}
-	
-	if visibility != "" {
-		params.Set("visibility", visibility)
-	}
-	
-	if len(tags) > 0 {
-		params.Set("tags", strings.Join(tags, ","))
-	}
+// SearchProjects streams every project whose name or key contains query, or
+// all projects when query is empty
+func (c *Client) SearchProjects(ctx context.Context, query string) *Iterator[Project] {
+	req := newRequest(http.MethodGet, "projects/search").
+		SetIfNotEmpty("q", query)
 
-	resp, err := c.doRequest("POST", "projects/update", params)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return newIterator[Project](ctx, c, req, "components")
+}
+
+func (c *Client) UpdateProject(ctx context.Context, key string, name string, visibility string, tags []string) (*Project, error) {
+	req := newRequest(http.MethodPost, "projects/update").
+		Set("project", key).
+		SetIfNotEmpty("name", name).
+		SetIfNotEmpty("visibility", visibility).
+		SetList("tags", tags)
 
-	var project Project
-	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+	if err := c.call(ctx, req); err != nil {
 		return nil, err
 	}
 
-	return &project, nil
+	return c.ReadProject(ctx, key)
 }
 
-func (c *Client) DeleteProject(key string) error {
-	params := url.Values{}
-	params.Set("project", key)
+func (c *Client) DeleteProject(ctx context.Context, key string) error {
+	req := newRequest(http.MethodPost, "projects/delete").
+		Set("project", key)
 
-	resp, err := c.doRequest("POST", "projects/delete", params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	return nil
+	return c.call(ctx, req)
 }
 
 // Quality Gate API Methods
-func (c *Client) CreateQualityGate(name string) (*QualityGate, error) {
-	params := url.Values{}
-	params.Set("name", name)
+//
+// Quality gates are identified by name on servers that support
+// CapQualityGateByName and by numeric id on older servers. The identifier
+// returned in QualityGate.ID is whichever one the server expects.
+
+// qualityGateParam returns the parameter name used to address a quality gate
+// on this server, e.g. "gateName" instead of "gateId"
+func (c *Client) qualityGateParam(byName, byID string) string {
+	if c.supports(CapQualityGateByName) {
+		return byName
+	}
+	return byID
+}
+
+func (c *Client) CreateQualityGate(ctx context.Context, name string) (*QualityGate, error) {
+	req := newRequest(http.MethodPost, "qualitygates/create").
+		Set("name", name)
 
-	resp, err := c.doRequest("POST", "qualitygates/create", params)
+	gate, err := doJSON[QualityGate](ctx, c, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var gate QualityGate
-	if err := json.NewDecoder(resp.Body).Decode(&gate); err != nil {
-		return nil, err
+	if c.supports(CapQualityGateByName) {
+		gate.ID = gate.Name
 	}
+	return gate, nil
+}
+
+func (c *Client) CreateQualityGateCondition(ctx context.Context, gate, metric, op, error string) (*Condition, error) {
+	req := newRequest(http.MethodPost, "qualitygates/create_condition").
+		Set(c.qualityGateParam("gateName", "gateId"), gate).
+		Set("metric", metric).
+		Set("op", op).
+		Set("error", error)
+
+	return doJSON[Condition](ctx, c, req)
+}
+
+func (c *Client) DeleteQualityGateCondition(ctx context.Context, id string) error {
+	req := newRequest(http.MethodPost, "qualitygates/delete_condition").
+		Set("id", id)
 
-	return &gate, nil
+	return c.call(ctx, req)
 }
 
-func (c *Client) CreateQualityGateCondition(gateID, metric, op, error string) (*Condition, error) {
-	params := url.Values{}
-	params.Set("gateId", gateID)
-	params.Set("metric", metric)
-	params.Set("op", op)
-	params.Set("error", error)
+func (c *Client) ReadQualityGate(ctx context.Context, gate string) (*QualityGate, error) {
+	req := newRequest(http.MethodGet, "qualitygates/show").
+		Set(c.qualityGateParam("name", "id"), gate)
 
-	resp, err := c.doRequest("POST", "qualitygates/create_condition", params)
+	result, err := doJSON[QualityGate](ctx, c, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var condition Condition
-	if err := json.NewDecoder(resp.Body).Decode(&condition); err != nil {
-		return nil, err
+	if c.supports(CapQualityGateByName) {
+		result.ID = result.Name
 	}
-
-	return &condition, nil
+	return result, nil
 }
 
-func (c *Client) ReadQualityGate(id string) (*QualityGate, error) {
-	params := url.Values{}
-	params.Set("id", id)
+// GetQualityGateByName looks up a quality gate by name on any server
+// version, returning it under the identifier the server expects
+func (c *Client) GetQualityGateByName(ctx context.Context, name string) (*QualityGate, error) {
+	req := newRequest(http.MethodGet, "qualitygates/show").
+		Set("name", name)
 
-	resp, err := c.doRequest("GET", "qualitygates/show", params)
+	gate, err := doJSON[QualityGate](ctx, c, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var gate QualityGate
-	if err := json.NewDecoder(resp.Body).Decode(&gate); err != nil {
-		return nil, err
+	if c.supports(CapQualityGateByName) {
+		gate.ID = gate.Name
 	}
-
-	return &gate, nil
+	return gate, nil
 }
 
-func (c *Client) UpdateQualityGate(id, name string) (*QualityGate, error) {
-	params := url.Values{}
-	params.Set("id", id)
-	params.Set("name", name)
+// UpdateQualityGate renames a quality gate and returns it under its new
+// identifier, which changes on servers that address gates by name
+func (c *Client) UpdateQualityGate(ctx context.Context, gate, name string) (*QualityGate, error) {
+	req := newRequest(http.MethodPost, "qualitygates/rename").
+		Set(c.qualityGateParam("currentName", "id"), gate).
+		Set("name", name)
 
-	resp, err := c.doRequest("POST", "qualitygates/update", params)
-	if err != nil {
+	if err := c.call(ctx, req); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var gate QualityGate
-	if err := json.NewDecoder(resp.Body).Decode(&gate); err != nil {
-		return nil, err
+	if c.supports(CapQualityGateByName) {
+		gate = name
 	}
-
-	return &gate, nil
+	return c.ReadQualityGate(ctx, gate)
 }
 
-func (c *Client) DeleteQualityGate(id string) error {
-	params := url.Values{}
-	params.Set("id", id)
-
-	resp, err := c.doRequest("POST", "qualitygates/delete", params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+func (c *Client) DeleteQualityGate(ctx context.Context, gate string) error {
+	req := newRequest(http.MethodPost, "qualitygates/destroy").
+		Set(c.qualityGateParam("name", "id"), gate)
 
-	return nil
+	return c.call(ctx, req)
 }
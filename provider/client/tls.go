@@ -0,0 +1,83 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// TLSOptions describes how the client verifies the SonarQube server and
+// authenticates itself to it at the TLS layer
+type TLSOptions struct {
+	// CABundle holds PEM certificates trusted in addition to the system
+	// roots, for servers behind an internal CA
+	CABundle []byte
+
+	// ClientCert and ClientKey are a PEM certificate and key presented to
+	// servers or ingresses that require mutual TLS. Set both or neither.
+	ClientCert []byte
+	ClientKey  []byte
+
+	// MinVersion is the lowest TLS version accepted, e.g. tls.VersionTLS13.
+	// Zero means TLS 1.2.
+	MinVersion uint16
+
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool
+}
+
+// NewTLSConfig builds the TLS configuration for WithTLSConfig from opts
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         opts.MinVersion,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if len(opts.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(opts.CABundle) {
+			return nil, errors.New("CA bundle contains no PEM certificates")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(opts.ClientCert) > 0 || len(opts.ClientKey) > 0 {
+		if len(opts.ClientCert) == 0 || len(opts.ClientKey) == 0 {
+			return nil, errors.New("a client certificate and key must be given together")
+		}
+		cert, err := tls.X509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to SonarQube,
+// usually built with NewTLSConfig
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// applyTLSConfig installs c.tlsConfig on transport, if it is one that takes
+// a TLS configuration
+func (c *Client) applyTLSConfig(transport http.RoundTripper) {
+	if c.tlsConfig == nil {
+		return
+	}
+	if t, ok := transport.(*http.Transport); ok {
+		t.TLSClientConfig = c.tlsConfig.Clone()
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTLSTestServer(t *testing.T, configure func(*tls.Config)) (*httptest.Server, []byte) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"paging":{"total":1},"components":[{"key":"demo"}]}`))
+	}))
+	srv.TLS = &tls.Config{}
+	if configure != nil {
+		configure(srv.TLS)
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	return srv, ca
+}
+
+// newClientCertificate returns a self-signed client certificate and key
+func newClientCertificate(t *testing.T) (certPEM, keyPEM []byte, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), cert
+}
+
+func newTLSTestClient(t *testing.T, url string, opts TLSOptions) *Client {
+	t.Helper()
+	cfg, err := NewTLSConfig(opts)
+	require.NoError(t, err)
+	return NewClient(url, "token", WithTLSConfig(cfg), WithRetryConfig(RetryConfig{MaxRetries: 0}))
+}
+
+func TestTLSCABundle(t *testing.T) {
+	srv, ca := newTLSTestServer(t, nil)
+	ctx := context.Background()
+
+	_, err := newTLSTestClient(t, srv.URL, TLSOptions{}).ReadProject(ctx, "demo")
+	assert.Error(t, err, "the test server's CA is not a system root")
+
+	_, err = newTLSTestClient(t, srv.URL, TLSOptions{CABundle: ca}).ReadProject(ctx, "demo")
+	assert.NoError(t, err)
+
+	_, err = newTLSTestClient(t, srv.URL, TLSOptions{InsecureSkipVerify: true}).ReadProject(ctx, "demo")
+	assert.NoError(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{CABundle: []byte("not a certificate")})
+	assert.EqualError(t, err, "CA bundle contains no PEM certificates")
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	certPEM, keyPEM, cert := newClientCertificate(t)
+	srv, ca := newTLSTestServer(t, func(cfg *tls.Config) {
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(cert)
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	})
+	ctx := context.Background()
+
+	_, err := newTLSTestClient(t, srv.URL, TLSOptions{CABundle: ca}).ReadProject(ctx, "demo")
+	assert.Error(t, err, "the server requires a client certificate")
+
+	_, err = newTLSTestClient(t, srv.URL, TLSOptions{CABundle: ca, ClientCert: certPEM, ClientKey: keyPEM}).ReadProject(ctx, "demo")
+	assert.NoError(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{ClientCert: certPEM})
+	assert.Error(t, err, "a certificate without its key is rejected")
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	srv, ca := newTLSTestServer(t, func(cfg *tls.Config) {
+		cfg.MaxVersion = tls.VersionTLS12
+	})
+	ctx := context.Background()
+
+	_, err := newTLSTestClient(t, srv.URL, TLSOptions{CABundle: ca}).ReadProject(ctx, "demo")
+	assert.NoError(t, err)
+
+	_, err = newTLSTestClient(t, srv.URL, TLSOptions{CABundle: ca, MinVersion: tls.VersionTLS13}).ReadProject(ctx, "demo")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"golang.org/x/net/http/httpproxy"
+	"net/http"
+	"net/url"
+)
+
+// WithProxy sends requests through proxyURL instead of the proxy named by
+// HTTPS_PROXY or HTTP_PROXY. noProxy lists hosts to reach directly, in the
+// NO_PROXY format, and replaces NO_PROXY. Either may be empty to keep the
+// environment's setting.
+func WithProxy(proxyURL, noProxy string) ClientOption {
+	return func(c *Client) {
+		c.proxyURL = proxyURL
+		c.noProxy = noProxy
+	}
+}
+
+// WithHeaders adds headers to every request, e.g. for an API gateway in
+// front of SonarQube. They cannot replace the Authorization and Accept
+// headers the client sets.
+func WithHeaders(headers map[string]string) ClientOption {
+	return func(c *Client) {
+		c.headers = headers
+	}
+}
+
+// applyProxy installs the proxy settings on transport. Requests to
+// localhost are never proxied.
+func (c *Client) applyProxy(transport http.RoundTripper) {
+	if c.proxyURL == "" && c.noProxy == "" {
+		return
+	}
+	t, ok := transport.(*http.Transport)
+	if !ok {
+		return
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	if c.proxyURL != "" {
+		cfg.HTTPProxy, cfg.HTTPSProxy = c.proxyURL, c.proxyURL
+	}
+	if c.noProxy != "" {
+		cfg.NoProxy = c.noProxy
+	}
+
+	proxy := cfg.ProxyFunc()
+	t.Proxy = func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}
+}
+
+func (c *Client) setExtraHeaders(h http.Header) {
+	for name, value := range c.headers {
+		h.Set(name, value)
+	}
+}
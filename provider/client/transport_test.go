@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyRoutesRequests(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = w.Write([]byte(`{"paging":{"total":1},"components":[{"key":"demo"}]}`))
+	}))
+	defer proxy.Close()
+
+	ctx := context.Background()
+	noRetries := WithRetryConfig(RetryConfig{MaxRetries: 0})
+
+	c := NewClient("http://sonarqube.invalid/sonarqube/", "token", WithProxy(proxy.URL, ""), noRetries)
+	_, err := c.ReadProject(ctx, "demo")
+	require.NoError(t, err)
+	require.Len(t, proxied, 1)
+	assert.Equal(t, "http://sonarqube.invalid/sonarqube/api/projects/search?p=1&projects=demo&ps=500", proxied[0])
+
+	c = NewClient("http://sonarqube.invalid/sonarqube/", "token", WithProxy(proxy.URL, ".invalid"), noRetries)
+	_, err = c.ReadProject(ctx, "demo")
+	assert.Error(t, err, "no_proxy hosts are dialed directly")
+	assert.Len(t, proxied, 1)
+}
+
+func TestExtraHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gateway-key", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "token", WithHeaders(map[string]string{
+		"X-Api-Key":     "gateway-key",
+		"Authorization": "Basic ignored",
+	}))
+	require.NoError(t, c.DeleteProject(context.Background(), "demo"))
+}
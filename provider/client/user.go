@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// User represents a SonarQube user
+type User struct {
+	Login            string   `json:"login"`
+	Name             string   `json:"name"`
+	Email            string   `json:"email,omitempty"`
+	Active           bool     `json:"active"`
+	Local            bool     `json:"local"`
+	ScmAccounts      []string `json:"scmAccounts,omitempty"`
+	Groups           []string `json:"groups,omitempty"`
+	ExternalIdentity string   `json:"externalIdentity,omitempty"`
+	ExternalProvider string   `json:"externalProvider,omitempty"`
+}
+
+// SearchUsers streams every active user whose login, name or email contains
+// query, or all active users when query is empty
+func (c *Client) SearchUsers(ctx context.Context, query string) *Iterator[User] {
+	req := newRequest(http.MethodGet, "users/search").
+		SetIfNotEmpty("q", query)
+
+	return newIterator[User](ctx, c, req, "users")
+}
+
+// GetUser looks up a single active user by exact login
+func (c *Client) GetUser(ctx context.Context, login string) (*User, error) {
+	return findUser(c.SearchUsers(ctx, login), login)
+}
+
+// GetDeactivatedUser looks up a single deactivated user by exact login
+func (c *Client) GetDeactivatedUser(ctx context.Context, login string) (*User, error) {
+	req := newRequest(http.MethodGet, "users/search").
+		Set("q", login).
+		Set("deactivated", "true")
+
+	return findUser(newIterator[User](ctx, c, req, "users"), login)
+}
+
+func findUser(it *Iterator[User], login string) (*User, error) {
+	for it.Next() {
+		if user := it.Item(); user.Login == login {
+			return &user, nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, newNotFoundError("users/search", "user not found: %s", login)
+}
+
+// setScmAccounts sends accounts as repeated scmAccount parameters. An empty
+// list is sent as a single empty value, which clears the accounts.
+func setScmAccounts(req *Request, accounts []string) *Request {
+	if len(accounts) == 0 {
+		return req.Set("scmAccount", "")
+	}
+	return req.Add("scmAccount", accounts...)
+}
+
+// CreateUser creates user. Local users need a password; external users,
+// authenticated by an identity provider, must not have one. Creating the
+// login of a deactivated user reactivates it.
+func (c *Client) CreateUser(ctx context.Context, user *User, password string) (*User, error) {
+	req := newRequest(http.MethodPost, "users/create").
+		Set("login", user.Login).
+		Set("name", user.Name).
+		SetIfNotEmpty("email", user.Email).
+		SetIfNotEmpty("password", password).
+		Set("local", strconv.FormatBool(user.Local))
+	if len(user.ScmAccounts) > 0 {
+		req.Add("scmAccount", user.ScmAccounts...)
+	}
+
+	result, err := doJSON[struct {
+		User User `json:"user"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.User, nil
+}
+
+// UpdateUser replaces the name, email and SCM accounts of an active user
+func (c *Client) UpdateUser(ctx context.Context, login, name, email string, scmAccounts []string) (*User, error) {
+	req := setScmAccounts(newRequest(http.MethodPost, "users/update").
+		Set("login", login).
+		Set("name", name).
+		Set("email", email), scmAccounts)
+
+	result, err := doJSON[struct {
+		User User `json:"user"`
+	}](ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.User, nil
+}
+
+// UpdateUserLogin renames a user, keeping its groups, permissions and
+// tokens
+func (c *Client) UpdateUserLogin(ctx context.Context, login, newLogin string) error {
+	req := newRequest(http.MethodPost, "users/update_login").
+		Set("login", login).
+		Set("newLogin", newLogin)
+
+	return c.call(ctx, req)
+}
+
+// ChangeUserPassword sets the password of a local user
+func (c *Client) ChangeUserPassword(ctx context.Context, login, password string) error {
+	req := newRequest(http.MethodPost, "users/change_password").
+		Set("login", login).
+		Set("password", password)
+
+	return c.call(ctx, req)
+}
+
+// DeactivateUser deactivates a user, removing its group memberships,
+// permissions and SCM accounts. SonarQube never deletes users.
+func (c *Client) DeactivateUser(ctx context.Context, login string) error {
+	req := newRequest(http.MethodPost, "users/deactivate").
+		Set("login", login)
+
+	return c.call(ctx, req)
+}
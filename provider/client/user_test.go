@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestUserLifecycle(t *testing.T) {
+	fake := fakesonar.NewServer()
+	defer fake.Close()
+	c := NewClient(fake.URL, fakesonar.DefaultToken)
+	ctx := context.Background()
+
+	created, err := c.CreateUser(ctx, &User{Login: "jdoe", Name: "J. Doe", Local: true, ScmAccounts: []string{"jdoe@example.com"}}, "s3cret-pass")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jdoe@example.com"}, created.ScmAccounts)
+	assert.Equal(t, "s3cret-pass", fake.User("jdoe").Password)
+
+	updated, err := c.UpdateUser(ctx, "jdoe", "Jane Doe", "jane@example.com", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", updated.Name)
+	assert.Empty(t, updated.ScmAccounts, "an empty list clears the SCM accounts")
+
+	require.NoError(t, c.ChangeUserPassword(ctx, "jdoe", "n3w-pass"))
+	assert.Equal(t, "n3w-pass", fake.User("jdoe").Password)
+
+	require.NoError(t, c.UpdateUserLogin(ctx, "jdoe", "jane"))
+	_, err = c.GetUser(ctx, "jdoe")
+	assert.True(t, IsNotFound(err))
+
+	require.NoError(t, c.DeactivateUser(ctx, "jane"))
+	_, err = c.GetUser(ctx, "jane")
+	assert.True(t, IsNotFound(err))
+	deactivated, err := c.GetDeactivatedUser(ctx, "jane")
+	require.NoError(t, err)
+	assert.False(t, deactivated.Active)
+
+	_, err = c.CreateUser(ctx, &User{Login: "jane", Name: "Jane Doe", Local: false}, "")
+	require.NoError(t, err)
+	reactivated, err := c.GetUser(ctx, "jane")
+	require.NoError(t, err)
+	assert.False(t, reactivated.Local)
+}
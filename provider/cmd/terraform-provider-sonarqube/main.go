@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/tomer1983/terraform-provider-sonarqube"
+	"log"
+	"time"
+)
+
+// shutdownTimeout bounds the telemetry flush after Terraform releases the
+// plugin. Terraform kills plugins that haven't exited 2s after it asks them
+// to, so the flush has to finish well inside that.
+const shutdownTimeout = time.Second
+
+func main() {
+	plugin.Serve(&plugin.ServeOpts{
+		ProviderFunc: provider.Provider,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("[ERROR] %s", err)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
 )
 
 func dataSourceSonarqubeProject() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceProjectRead,
+		ReadContext: instrumented("data.sonarqube_project", "read", dataSourceProjectRead),
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -39,12 +40,12 @@ func dataSourceSonarqubeProject() *schema.Resource {
 }
 
 func dataSourceProjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	projects := m.(client.Projects)
 
 	key := d.Get("key").(string)
-	project, err := client.GetProject(key)
+	project, err := projects.ReadProject(ctx, key)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(project.Key)
@@ -58,7 +59,7 @@ func dataSourceProjectRead(ctx context.Context, d *schema.ResourceData, m interf
 
 func dataSourceSonarqubeQualityGate() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceQualityGateRead,
+		ReadContext: instrumented("data.sonarqube_quality_gate", "read", dataSourceQualityGateRead),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -90,12 +91,12 @@ func dataSourceSonarqubeQualityGate() *schema.Resource {
 }
 
 func dataSourceQualityGateRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	gates := m.(client.QualityGates)
 
 	name := d.Get("name").(string)
-	gate, err := client.GetQualityGateByName(name)
+	gate, err := gates.GetQualityGateByName(ctx, name)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(gate.ID)
@@ -116,7 +117,7 @@ func dataSourceQualityGateRead(ctx context.Context, d *schema.ResourceData, m in
 
 func dataSourceSonarqubePortfolio() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourcePortfolioRead,
+		ReadContext: instrumented("data.sonarqube_portfolio", "read", dataSourcePortfolioRead),
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -169,12 +170,12 @@ func dataSourceSonarqubePortfolio() *schema.Resource {
 }
 
 func dataSourcePortfolioRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	portfolios := m.(client.Portfolios)
 
 	key := d.Get("key").(string)
-	portfolio, err := client.GetPortfolio(key)
+	portfolio, err := portfolios.GetPortfolio(ctx, key)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(portfolio.Key)
@@ -4,11 +4,12 @@ import (
 	"context"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
 )
 
 func dataSourceSonarqubeUser() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceUserRead,
+		ReadContext: instrumented("data.sonarqube_user", "read", dataSourceUserRead),
 
 		Schema: map[string]*schema.Schema{
 			"login": {
@@ -36,12 +37,12 @@ func dataSourceSonarqubeUser() *schema.Resource {
 }
 
 func dataSourceUserRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	users := m.(client.Users)
 
 	login := d.Get("login").(string)
-	user, err := client.GetUser(login)
+	user, err := users.GetUser(ctx, login)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(user.Login)
@@ -55,7 +56,7 @@ func dataSourceUserRead(ctx context.Context, d *schema.ResourceData, m interface
 
 func dataSourceSonarqubeGroup() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceGroupRead,
+		ReadContext: instrumented("data.sonarqube_group", "read", dataSourceGroupRead),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -79,12 +80,12 @@ func dataSourceSonarqubeGroup() *schema.Resource {
 }
 
 func dataSourceGroupRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	groups := m.(client.Groups)
 
 	name := d.Get("name").(string)
-	group, err := client.GetGroup(name)
+	group, err := groups.GetGroup(ctx, name)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(group.Name)
@@ -97,7 +98,7 @@ func dataSourceGroupRead(ctx context.Context, d *schema.ResourceData, m interfac
 
 func dataSourceSonarqubeMetric() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceMetricRead,
+		ReadContext: instrumented("data.sonarqube_metric", "read", dataSourceMetricRead),
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -125,12 +126,12 @@ func dataSourceSonarqubeMetric() *schema.Resource {
 }
 
 func dataSourceMetricRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	catalog := m.(client.Catalog)
 
 	key := d.Get("key").(string)
-	metric, err := client.GetMetric(key)
+	metric, err := catalog.GetMetric(ctx, key)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(metric.Key)
@@ -144,7 +145,7 @@ func dataSourceMetricRead(ctx context.Context, d *schema.ResourceData, m interfa
 
 func dataSourceSonarqubeLanguage() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceLanguageRead,
+		ReadContext: instrumented("data.sonarqube_language", "read", dataSourceLanguageRead),
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -167,24 +168,34 @@ func dataSourceSonarqubeLanguage() *schema.Resource {
 }
 
 func dataSourceLanguageRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	catalog := m.(client.Catalog)
 
 	key := d.Get("key").(string)
-	language, err := client.GetLanguage(key)
+	language, err := catalog.GetLanguage(ctx, key)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
+	}
+
+	// Suffixes are a setting of the language's analyzer, not part of languages/list
+	var suffixes []string
+	settings, err := m.(client.Settings).GetSettings(ctx, "", "sonar."+language.Key+".file.suffixes")
+	if err != nil {
+		return apiError(ctx, err)
+	}
+	for _, setting := range settings {
+		suffixes = append(suffixes, setting.Values...)
 	}
 
 	d.SetId(language.Key)
 	d.Set("name", language.Name)
-	d.Set("file_suffixes", language.FileSuffixes)
+	d.Set("file_suffixes", suffixes)
 
 	return nil
 }
 
 func dataSourceSonarqubeRule() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: dataSourceRuleRead,
+		ReadContext: instrumented("data.sonarqube_rule", "read", dataSourceRuleRead),
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -224,12 +235,12 @@ func dataSourceSonarqubeRule() *schema.Resource {
 }
 
 func dataSourceRuleRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	catalog := m.(client.Catalog)
 
 	key := d.Get("key").(string)
-	rule, err := client.GetRule(key)
+	rule, err := catalog.GetRule(ctx, key)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(rule.Key)
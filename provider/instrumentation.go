@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/metrics"
+	"log"
+	"strings"
+	"time"
+)
+
+// crudFunc is the shared signature of schema Create/Read/Update/Delete
+// context functions
+type crudFunc = func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics
+
+// defaultOperationTimeout bounds each resource operation unless the
+// resource's timeouts block says otherwise
+const defaultOperationTimeout = 10 * time.Minute
+
+// resourceTimeouts allows a timeouts block on a resource. The SDK applies
+// it as the deadline of the operation's context, which every API call the
+// operation makes inherits.
+func resourceTimeouts() *schema.ResourceTimeout {
+	return &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(defaultOperationTimeout),
+		Read:   schema.DefaultTimeout(defaultOperationTimeout),
+		Update: schema.DefaultTimeout(defaultOperationTimeout),
+		Delete: schema.DefaultTimeout(defaultOperationTimeout),
+	}
+}
+
+// resourceOperation tracks one CRUD call so errors can be attributed to it
+type resourceOperation struct {
+	resource  string
+	operation string
+	failed    bool
+}
+
+type resourceOperationKey struct{}
+
+// instrumented wraps a CRUD function so each call is counted, timed and
+// traced, and its failures are recorded by error type
+func instrumented(resource, operation string, fn crudFunc) crudFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		op := &resourceOperation{resource: resource, operation: operation}
+
+		ctx, span := startOperationSpan(ctx, resource, operation, d)
+		ctx, auditDone := audit.WithResource(ctx, resource, d.Id(), operation)
+		ctx, intercepted := client.CollectChanges(ctx)
+		start := time.Now()
+		diags := fn(context.WithValue(ctx, resourceOperationKey{}, op), d, m)
+		metrics.RecordResourceOperation(resource, operation, time.Since(start))
+		endOperationSpan(span, diags)
+
+		// A create only knows the resource's ID now
+		if err := auditDone(d.Id()); err != nil {
+			log.Printf("[ERROR] %s", err)
+		}
+
+		if changes := intercepted(); len(changes) > 0 {
+			diags = append(diags, dryRunWarning(changes))
+		}
+
+		// Errors that did not come from the API, such as failing to set state
+		if diags.HasError() && !op.failed {
+			metrics.RecordResourceError(resource, operation, client.ErrorTypeOther)
+		}
+
+		return diags
+	}
+}
+
+// apiError records err against the resource operation running in ctx and
+// converts it to diagnostics. Use it for errors returned by the client.
+func apiError(ctx context.Context, err error) diag.Diagnostics {
+	if op, ok := ctx.Value(resourceOperationKey{}).(*resourceOperation); ok && !op.failed {
+		op.failed = true
+		metrics.RecordResourceError(op.resource, op.operation, client.ClassifyError(err))
+	}
+
+	// Failed Compute Engine tasks carry the server's stack trace, which is
+	// too long for the summary
+	var taskErr *client.TaskError
+	if errors.As(err, &taskErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  taskErr.Error(),
+			Detail:   taskErr.Task.ErrorStacktrace,
+		}}
+	}
+	return diag.FromErr(err)
+}
+
+// dryRunWarning lists the API calls a dry_run provider intercepted during an
+// operation
+func dryRunWarning(changes []client.Change) diag.Diagnostic {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  fmt.Sprintf("Dry run: %d SonarQube API calls were not sent", len(changes)),
+		Detail:   strings.Join(lines, "\n"),
+	}
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/metrics"
+	"os"
+	"sync"
+)
+
+// The plugin process outlives any single ConfigureProvider call, and with
+// provider aliases may be configured several times. Anything that must live
+// as long as the process, such as the metrics endpoint, hangs off
+// processCtx and is torn down by Shutdown.
+var (
+	processCtx, stopProcess = context.WithCancel(context.Background())
+
+	lifecycleMu    sync.Mutex
+	metricsServers = map[string]bool{}
+	dryRunReports  = map[string][]*client.DryRun{}
+	shutdownHooks  []func(context.Context) error
+)
+
+// onShutdown registers f to run when the plugin process exits
+func onShutdown(f func(context.Context) error) {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+	shutdownHooks = append(shutdownHooks, f)
+}
+
+// Shutdown flushes process-wide telemetry. Call it after plugin.Serve
+// returns.
+func Shutdown(ctx context.Context) error {
+	lifecycleMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	lifecycleMu.Unlock()
+
+	err := runShutdownHooks(ctx, hooks)
+	stopProcess()
+	return err
+}
+
+// runShutdownHooks runs the hooks concurrently, so a slow trace export
+// can't hold up the audit log, metrics textfile and dry-run report, which
+// are local writes, until Terraform kills the process
+func runShutdownHooks(ctx context.Context, hooks []func(context.Context) error) error {
+	errs := make([]error, len(hooks))
+	var wg sync.WaitGroup
+	for i, hook := range hooks {
+		wg.Add(1)
+		go func(i int, hook func(context.Context) error) {
+			defer wg.Done()
+			errs[i] = hook(ctx)
+		}(i, hook)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// configureMetrics starts the Prometheus endpoint or schedules the textfile
+// export selected in the provider block
+func configureMetrics(d *schema.ResourceData) diag.Diagnostics {
+	addr := d.Get("metrics_listen_address").(string)
+	textfile := d.Get("metrics_textfile").(string)
+
+	if addr != "" && textfile != "" {
+		return diag.Errorf("only one of metrics_listen_address and metrics_textfile can be set")
+	}
+
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+
+	if addr != "" && !metricsServers[addr] {
+		if err := metrics.StartMetricsServer(processCtx, addr); err != nil {
+			return diag.FromErr(err)
+		}
+		metricsServers[addr] = true
+	}
+
+	if textfile != "" && !metricsServers[textfile] {
+		metricsServers[textfile] = true
+		shutdownHooks = append(shutdownHooks, func(context.Context) error {
+			if err := metrics.WriteTextfile(textfile); err != nil {
+				return fmt.Errorf("exporting metrics: %w", err)
+			}
+			return nil
+		})
+	}
+
+	return nil
+}
+
+// configureDryRun returns the overlay for a provider block with dry_run set,
+// or nil. With dry_run_report set, the calls it intercepts are written to
+// the report when the process exits, together with those of any other
+// provider block sharing the report. dry_run_report is checked here rather
+// than with RequiredWith, as its environment default always counts as set.
+func configureDryRun(d *schema.ResourceData) (*client.DryRun, diag.Diagnostics) {
+	report := d.Get("dry_run_report").(string)
+	if !d.Get("dry_run").(bool) {
+		if report != "" {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "dry_run_report requires dry_run",
+				Detail:        "dry_run_report lists the calls intercepted by dry_run, so it can only be set together with dry_run = true.",
+				AttributePath: cty.GetAttrPath("dry_run_report"),
+			}}
+		}
+		return nil, nil
+	}
+	dryRun := client.NewDryRun()
+
+	if report == "" {
+		return dryRun, nil
+	}
+
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+
+	if _, ok := dryRunReports[report]; !ok {
+		shutdownHooks = append(shutdownHooks, func(context.Context) error {
+			return writeDryRunReport(report)
+		})
+	}
+	dryRunReports[report] = append(dryRunReports[report], dryRun)
+	return dryRun, nil
+}
+
+func writeDryRunReport(path string) error {
+	lifecycleMu.Lock()
+	dryRuns := dryRunReports[path]
+	lifecycleMu.Unlock()
+
+	changes := []client.Change{}
+	for _, dryRun := range dryRuns {
+		changes = append(changes, dryRun.Changes()...)
+	}
+
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding dry run report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing dry run report: %w", err)
+	}
+	return nil
+}
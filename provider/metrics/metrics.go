@@ -2,8 +2,15 @@ package metrics
 
 import (
 	"context"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,26 +25,76 @@ var (
 		Help: "Total number of SonarQube API requests",
 	}, []string{"method", "path", "status"})
 
+	apiRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sonarqube_api_request_errors_total",
+		Help: "Total number of failed SonarQube API requests",
+	}, []string{"method", "path", "error_type"})
+
 	resourceOperationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "sonarqube_resource_operations_total",
 		Help: "Total number of resource operations",
 	}, []string{"resource", "operation"})
 
+	resourceOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sonarqube_resource_operation_duration_seconds",
+		Help:    "Duration of resource operations in seconds, including all API requests they make",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"resource", "operation"})
+
 	resourceOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "sonarqube_resource_operation_errors_total",
 		Help: "Total number of resource operation errors",
 	}, []string{"resource", "operation", "error_type"})
 )
 
-// RecordAPIRequest records metrics for an API request
+// idSegment matches path segments that identify a single object in the v2
+// API: numbers, UUIDs and SonarQube's 20 character base64 uuids
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F-]{27}|[A-Za-z0-9_-]{20,})$`)
+
+// NormalizePath reduces an API path to a template such as
+// "v2/authorizations/groups/{id}", so label cardinality stays bounded.
+// Query strings and the /api/ prefix are dropped.
+func NormalizePath(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), "api/")
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		// Endpoint names are lower case words, ids always mix in digits or capitals
+		if idSegment.MatchString(segment) && strings.ContainsAny(segment, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// statusLabel renders a status code label; 0 means no response was received
+func statusLabel(statusCode int) string {
+	if statusCode == 0 {
+		return "none"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+// RecordAPIRequest records metrics for an API request. path should already
+// be normalized with NormalizePath.
 func RecordAPIRequest(method, path string, statusCode int, duration time.Duration) {
-	apiRequestDuration.WithLabelValues(method, path, string(statusCode)).Observe(duration.Seconds())
-	apiRequestTotal.WithLabelValues(method, path, string(statusCode)).Inc()
+	status := statusLabel(statusCode)
+	apiRequestDuration.WithLabelValues(method, path, status).Observe(duration.Seconds())
+	apiRequestTotal.WithLabelValues(method, path, status).Inc()
+}
+
+// RecordAPIError records a failed API request by error type
+func RecordAPIError(method, path, errorType string) {
+	apiRequestErrors.WithLabelValues(method, path, errorType).Inc()
 }
 
 // RecordResourceOperation records metrics for a resource operation
-func RecordResourceOperation(resource, operation string) {
+func RecordResourceOperation(resource, operation string, duration time.Duration) {
 	resourceOperationsTotal.WithLabelValues(resource, operation).Inc()
+	resourceOperationDuration.WithLabelValues(resource, operation).Observe(duration.Seconds())
 }
 
 // RecordResourceError records metrics for a resource operation error
@@ -45,8 +102,47 @@ func RecordResourceError(resource, operation, errorType string) {
 	resourceOperationErrors.WithLabelValues(resource, operation, errorType).Inc()
 }
 
-// StartMetricsServer starts the metrics server
+// shutdownTimeout bounds how long in-flight scrapes may delay shutdown
+const shutdownTimeout = 5 * time.Second
+
+// StartMetricsServer serves the default registry at /metrics on addr until
+// ctx is canceled. It returns once the listener is bound, so an address
+// already in use is reported to the caller instead of being lost in the
+// background.
 func StartMetricsServer(ctx context.Context, addr string) error {
-	// Implementation for metrics server
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		// Serve returns http.ErrServerClosed once Shutdown is called
+		_ = srv.Serve(ln)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	return nil
+}
+
+// WriteTextfile writes the default registry to path in the text format read
+// by node_exporter's textfile collector. The file is replaced atomically so
+// the collector never sees a partial write.
+func WriteTextfile(path string) error {
+	if err := prometheus.WriteToTextfile(path, prometheus.DefaultGatherer); err != nil {
+		return fmt.Errorf("failed to write metrics textfile %s: %w", path, err)
+	}
 	return nil
 }
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizePath(t *testing.T) {
+	cases := map[string]string{
+		"projects/search": "projects/search",
+		"/api/qualitygates/create_condition?gateName=foo":                          "qualitygates/create_condition",
+		"v2/authorizations/groups/AY6lKq3tBrLoZFjNTxhb":                            "v2/authorizations/groups/{id}",
+		"v2/users-management/users/42":                                             "v2/users-management/users/{id}",
+		"v2/authorizations/group-memberships/5f8e3c1a-2b4d-4e6f-8a9b-0c1d2e3f4a5b": "v2/authorizations/group-memberships/{id}",
+		"alm_integrations/search_installations":                                    "alm_integrations/search_installations",
+	}
+	for path, want := range cases {
+		assert.Equal(t, want, NormalizePath(path), path)
+	}
+}
+
+func TestRecordAPIRequestStatusLabel(t *testing.T) {
+	RecordAPIRequest("GET", "projects/search", 404, time.Millisecond)
+	RecordAPIRequest("GET", "projects/search", 0, time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(apiRequestTotal.WithLabelValues("GET", "projects/search", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(apiRequestTotal.WithLabelValues("GET", "projects/search", "none")))
+}
+
+func TestStartMetricsServer(t *testing.T) {
+	// Reserve a free port, then hand it to the server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, StartMetricsServer(ctx, addr))
+
+	RecordResourceOperation("sonarqube_project", "create", time.Second)
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `sonarqube_resource_operations_total{operation="create",resource="sonarqube_project"}`)
+
+	// A second server cannot take the same address
+	assert.Error(t, StartMetricsServer(ctx, addr))
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := http.Get("http://" + addr + "/metrics")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWriteTextfile(t *testing.T) {
+	RecordAPIRequest("POST", "projects/create", 200, time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "sonarqube.prom")
+	require.NoError(t, WriteTextfile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# TYPE sonarqube_api_requests_total counter")
+	assert.Contains(t, string(data), `sonarqube_api_requests_total{method="POST",path="projects/create",status="200"}`)
+}
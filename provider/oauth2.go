@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+)
+
+func oauth2Schema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Get an access token with the OAuth2 client-credentials grant for a proxy in front of SonarQube, and send it with every request alongside the SonarQube credentials.",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"token_url": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+					Description:  "Token endpoint of the identity provider.",
+				},
+				"client_id": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "OAuth2 client ID.",
+				},
+				"client_secret": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Sensitive:   true,
+					Description: "OAuth2 client secret.",
+				},
+				"scopes": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "Scopes to request.",
+				},
+				"audience": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "`audience` parameter for identity providers that require one.",
+				},
+				"header": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     client.DefaultOAuth2Header,
+					Description: "Header the proxy reads the access token from, sent as `Bearer <token>`.",
+				},
+			},
+		},
+	}
+}
+
+// configureOAuth2 returns the client option for the oauth2 block, or nil
+// when it is not set
+func configureOAuth2(d *schema.ResourceData) client.ClientOption {
+	raw, ok := d.GetOk("oauth2")
+	if !ok || len(raw.([]interface{})) == 0 || raw.([]interface{})[0] == nil {
+		return nil
+	}
+	config := raw.([]interface{})[0].(map[string]interface{})
+
+	var scopes []string
+	for _, scope := range config["scopes"].([]interface{}) {
+		scopes = append(scopes, scope.(string))
+	}
+
+	return client.WithOAuth2(client.OAuth2Config{
+		TokenURL:     config["token_url"].(string),
+		ClientID:     config["client_id"].(string),
+		ClientSecret: config["client_secret"].(string),
+		Scopes:       scopes,
+		Audience:     config["audience"].(string),
+		Header:       config["header"].(string),
+	})
+}
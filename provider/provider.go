@@ -2,31 +2,235 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sirupsen/logrus"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"math"
+	"os"
+	"regexp"
+	"time"
 )
 
 func Provider() *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"host": {
-				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_HOST", nil),
+				Type:         schema.TypeString,
+				Required:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_HOST", nil),
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+				Description:  "SonarQube base URL, including any context path, e.g. `https://example.com/sonarqube`.",
 			},
 			"token": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_TOKEN", ""),
+				Description: "SonarQube token. One of `token`, `token_file`, `token_command` or `username` must be set.",
+			},
+			"token_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_TOKEN_FILE", ""),
+				Description: "File to read the SonarQube token from, e.g. a mounted secret.",
+			},
+			"token_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_TOKEN_COMMAND", ""),
+				Description: "Shell command printing the SonarQube token, e.g. a secret manager CLI. It runs on the first request and its output is cached.",
+			},
+			"token_command_ttl": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("SONARQUBE_TOKEN_COMMAND_TTL", ""),
+				ValidateDiagFunc: validateDuration,
+				Description:      "How long the output of `token_command` is cached, e.g. `15m`. By default it is cached until the provider exits.",
+			},
+			"username": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_USERNAME", ""),
+				Description: "Login of a local SonarQube user to authenticate as with basic auth, instead of a token. Requires `password`.",
+			},
+			"password": {
+				Type:        schema.TypeString,
+				Optional:    true,
 				Sensitive:   true,
-				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_TOKEN", nil),
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_PASSWORD", ""),
+				Description: "Password for `username`.",
+			},
+			"auth_mode": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_AUTH_MODE", string(client.AuthAuto)),
+				ValidateFunc: validation.StringInSlice(authModes, false),
+				Description:  "How the token is sent: `bearer`, `basic-token` (as the basic auth username, for SonarQube before 10.0), or `auto` to choose from the server version. `password` is implied by `username`.",
+			},
+			"proxy_url": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_PROXY_URL", ""),
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsURLWithScheme([]string{"http", "https", "socks5"})),
+				Description:  "Proxy to send SonarQube API requests through, instead of the one in `HTTPS_PROXY`/`HTTP_PROXY`.",
+			},
+			"no_proxy": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_NO_PROXY", ""),
+				Description: "Comma-separated hosts, domains and CIDR ranges to reach without the proxy, in `NO_PROXY` format. Replaces `NO_PROXY`.",
+			},
+			"extra_headers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Headers added to every SonarQube API request, e.g. for an API gateway. `Authorization` and `Accept` cannot be replaced.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"ca_cert": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_CA_CERT", ""),
+				Description: "PEM CA certificates to trust in addition to the system roots, or the path of a file holding them.",
+			},
+			"client_cert": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_CLIENT_CERT", ""),
+				Description: "PEM client certificate for mutual TLS, or the path of a file holding it. Requires `client_key`.",
+			},
+			"client_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_CLIENT_KEY", ""),
+				Description: "PEM private key of `client_cert`, or the path of a file holding it.",
+			},
+			"tls_min_version": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_TLS_MIN_VERSION", "1.2"),
+				ValidateFunc: validation.StringInSlice([]string{"1.0", "1.1", "1.2", "1.3"}, false),
+				Description:  "Lowest TLS version accepted from the server.",
+			},
+			"insecure_skip_verify": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_INSECURE_SKIP_VERIFY", false),
+				Description: "Do not verify the server certificate. Only for testing; prefer `ca_cert`.",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_MAX_CONCURRENT_REQUESTS", 0),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of SonarQube API requests in flight at once. 0 means unlimited.",
+			},
+			"requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_REQUESTS_PER_SECOND", 0.0),
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "Steady-state SonarQube API request rate. 0 means unlimited. Either way the rate backs off automatically on 429/503 responses.",
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_MAX_RETRIES", 3),
+				ValidateFunc: validation.IntBetween(0, 20),
+				Description:  "Number of times a failed SonarQube API request is retried. Only requests that are safe to repeat are retried.",
+			},
+			"retry_wait_min": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("SONARQUBE_RETRY_WAIT_MIN", "1s"),
+				ValidateDiagFunc: validateDuration,
+				Description:      "Wait before the first retry. Later waits double up to `retry_wait_max`, unless the server sends Retry-After.",
+			},
+			"retry_wait_max": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("SONARQUBE_RETRY_WAIT_MAX", "30s"),
+				ValidateDiagFunc: validateDuration,
+				Description:      "Longest wait between retries.",
+			},
+			"request_timeout": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("SONARQUBE_REQUEST_TIMEOUT", ""),
+				ValidateDiagFunc: validateDuration,
+				Description:      "Deadline for each HTTP attempt, e.g. `30s`. A timed out attempt is retried. Unset means no limit.",
+			},
+			"log_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_LOG_LEVEL", "info"),
+				ValidateFunc: validation.StringInSlice(logLevels, false),
+				Description:  "Level of the API client's log, written to Terraform's provider log: `trace`, `debug`, `info`, `warn` or `error`.",
+			},
+			"telemetry": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_TELEMETRY", true),
+				Description: "Trace every SonarQube API request as a child of its resource operation's span. Only takes effect with a `tracing` block.",
+			},
+			"call_timeout": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("SONARQUBE_CALL_TIMEOUT", ""),
+				ValidateDiagFunc: validateDuration,
+				Description:      "Deadline for each SonarQube API call, including its retries and backoff waits, e.g. `2m`. Resource `timeouts` blocks bound whole operations. Unset means no limit beyond the operation's.",
+			},
+			"audit_log": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_AUDIT_LOG", ""),
+				Description: "Path of a file to append a JSON line to for every change made in SonarQube, with secrets redacted.",
+			},
+			"dry_run": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_DRY_RUN", false),
+				Description: "Send reads to SonarQube but intercept every change, answering from an in-memory overlay. Intercepted calls are reported as warnings. Terraform still records the results in state, so only apply against a copy of the state.",
+			},
+			"dry_run_report": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_DRY_RUN_REPORT", ""),
+				Description: "Path of a JSON file listing every call intercepted by `dry_run`, written when the provider exits. Requires `dry_run`.",
+			},
+			"oauth2":  oauth2Schema(),
+			"tracing": tracingSchema(),
+			"metrics_listen_address": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("SONARQUBE_METRICS_LISTEN_ADDRESS", ""),
+				Description: "Address such as `127.0.0.1:9464` to serve Prometheus metrics on at `/metrics` while the provider runs. Conflicts with `metrics_textfile`.",
+			},
+			"metrics_textfile": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("SONARQUBE_METRICS_TEXTFILE", ""),
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.StringMatch(regexp.MustCompile(`\.prom$`), "must end in .prom to be read by the node_exporter textfile collector")),
+				Description:  "Path of a `.prom` file to write Prometheus metrics to when the provider exits, for the node_exporter textfile collector. Conflicts with `metrics_listen_address`.",
 			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"sonarqube_project":      resourceSonarqubeProject(),
-			"sonarqube_qualitygate":  resourceSonarqubeQualityGate(),
-			"sonarqube_user":         resourceSonarqubeUser(),
-			"sonarqube_group":        resourceSonarqubeGroup(),
-			"sonarqube_portfolio":    resourceSonarqubePortfolio(),
+			"sonarqube_project":          resourceSonarqubeProject(),
+			"sonarqube_qualitygate":      resourceSonarqubeQualityGate(),
+			"sonarqube_user":             resourceSonarqubeUser(),
+			"sonarqube_group":            resourceSonarqubeGroup(),
+			"sonarqube_group_member":     resourceSonarqubeGroupMember(),
+			"sonarqube_group_members":    resourceSonarqubeGroupMembers(),
+			"sonarqube_portfolio":        resourceSonarqubePortfolio(),
+			"sonarqube_projects_cleanup": resourceSonarqubeProjectsCleanup(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
 			"sonarqube_project":      dataSourceSonarqubeProject(),
@@ -42,19 +246,175 @@ func Provider() *schema.Provider {
 	}
 }
 
-type Client struct {
-	Host  string
-	Token string
-}
-
 func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 	host := d.Get("host").(string)
 	token := d.Get("token").(string)
 
-	client := &Client{
-		Host:  host,
-		Token: token,
+	auth, diags := configureAuth(d)
+	if diags.HasError() {
+		return nil, diags
 	}
 
-	return client, nil
+	if diags := configureMetrics(d); diags.HasError() {
+		return nil, diags
+	}
+
+	tracing, tracingDiags := configureTracing(ctx, d)
+	diags = append(diags, tracingDiags...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	requestsPerSecond := d.Get("requests_per_second").(float64)
+	burst := int(math.Ceil(requestsPerSecond))
+
+	retries, retryDiags := retryConfig(d)
+	diags = append(diags, retryDiags...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	tlsConfig, tlsDiags := configureTLS(d)
+	diags = append(diags, tlsDiags...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	opts := []client.ClientOption{
+		client.WithMaxConcurrency(d.Get("max_concurrent_requests").(int)),
+		client.WithRateLimit(requestsPerSecond, burst),
+		client.WithRetryConfig(retries),
+		client.WithTLSConfig(tlsConfig),
+		client.WithProxy(d.Get("proxy_url").(string), d.Get("no_proxy").(string)),
+		client.WithLogger(newClientLogger(d.Get("log_level").(string))),
+	}
+
+	// Tests record or replay HTTP traffic through a cassette, see DEVELOPMENT.md
+	if path := os.Getenv("SONARQUBE_CASSETTE"); path != "" {
+		mode := client.CassetteMode(os.Getenv("SONARQUBE_CASSETTE_MODE"))
+		if mode == "" {
+			mode = client.CassetteReplay
+		}
+		if mode != client.CassetteRecord && mode != client.CassetteReplay {
+			return nil, diag.Errorf("SONARQUBE_CASSETTE_MODE must be %q or %q, got %q", client.CassetteRecord, client.CassetteReplay, mode)
+		}
+		opts = append(opts, client.WithCassette(path, mode))
+	}
+
+	if v := d.Get("extra_headers").(map[string]interface{}); len(v) > 0 {
+		headers := make(map[string]string, len(v))
+		for name, value := range v {
+			headers[name] = value.(string)
+		}
+		opts = append(opts, client.WithHeaders(headers))
+	}
+
+	if tracing && d.Get("telemetry").(bool) {
+		opts = append(opts, client.WithTelemetry())
+	}
+
+	if path := d.Get("audit_log").(string); path != "" {
+		log, err := audit.Open(path)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		onShutdown(func(context.Context) error { return log.Close() })
+		opts = append(opts, client.WithAuditLog(log))
+	}
+
+	if oauth2 := configureOAuth2(d); oauth2 != nil {
+		opts = append(opts, oauth2)
+	}
+
+	dryRun, dryRunDiags := configureDryRun(d)
+	if dryRunDiags.HasError() {
+		return nil, dryRunDiags
+	}
+	if dryRun != nil {
+		opts = append(opts, client.WithDryRun(dryRun))
+	}
+
+	if v := d.Get("call_timeout").(string); v != "" {
+		timeout, _ := time.ParseDuration(v)
+		opts = append(opts, client.WithCallTimeout(timeout))
+	}
+	if v := d.Get("request_timeout").(string); v != "" {
+		timeout, _ := time.ParseDuration(v)
+		opts = append(opts, client.WithRequestTimeout(timeout))
+	}
+
+	opts = append(opts, auth...)
+
+	c := client.NewClient(host, token, opts...)
+
+	if _, err := c.DetectServer(ctx); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Unable to detect SonarQube version and edition",
+			Detail:   fmt.Sprintf("The provider will assume the latest API. %s", err),
+		})
+	}
+
+	return c, diags
+}
+
+// validateDuration accepts Go duration strings such as "90s" or "2m", and
+// the empty string of an unset attribute
+func validateDuration(v interface{}, path cty.Path) diag.Diagnostics {
+	if v.(string) == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v.(string))
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid duration",
+			Detail:        fmt.Sprintf("%q is not a duration such as \"90s\" or \"2m\": %s", v, err),
+			AttributePath: path,
+		}}
+	}
+	if d <= 0 {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid duration",
+			Detail:        fmt.Sprintf("%q must be positive", v),
+			AttributePath: path,
+		}}
+	}
+	return nil
+}
+
+// retryConfig reads the retry attributes, which have already been
+// validated as durations
+func retryConfig(d *schema.ResourceData) (client.RetryConfig, diag.Diagnostics) {
+	waitMin, _ := time.ParseDuration(d.Get("retry_wait_min").(string))
+	waitMax, _ := time.ParseDuration(d.Get("retry_wait_max").(string))
+	if waitMin > waitMax {
+		return client.RetryConfig{}, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid retry waits",
+			Detail:        fmt.Sprintf("retry_wait_min (%s) must not be longer than retry_wait_max (%s)", waitMin, waitMax),
+			AttributePath: cty.GetAttrPath("retry_wait_min"),
+		}}
+	}
+
+	return client.RetryConfig{
+		MaxRetries: d.Get("max_retries").(int),
+		WaitMin:    waitMin,
+		WaitMax:    waitMax,
+	}, nil
+}
+
+var logLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// newClientLogger logs to stderr, which Terraform collects into its
+// provider log. Terraform adds its own timestamps.
+func newClientLogger(level string) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetFormatter(&logrus.TextFormatter{DisableColors: true, DisableTimestamp: true})
+	if lvl, err := logrus.ParseLevel(level); err == nil {
+		logger.SetLevel(lvl)
+	}
+	return logger
 }
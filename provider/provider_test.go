@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProvider(t *testing.T) {
+	if err := Provider().InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestProviderConfigureClientOptions(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":            fake.URL,
+		"token":           fakesonar.DefaultToken,
+		"max_retries":     5,
+		"retry_wait_min":  "200ms",
+		"retry_wait_max":  "5s",
+		"request_timeout": "30s",
+		"log_level":       "debug",
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+	_, ok := p.Meta().(client.API)
+	assert.True(t, ok)
+
+	diags = Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":           fake.URL,
+		"token":          fakesonar.DefaultToken,
+		"retry_wait_min": "1m",
+		"retry_wait_max": "10s",
+	}))
+	require.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Detail, "retry_wait_min (1m0s) must not be longer than retry_wait_max (10s)")
+
+	diags = Provider().Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":      fake.URL,
+		"token":     fakesonar.DefaultToken,
+		"log_level": "verbose",
+	}))
+	assert.True(t, diags.HasError(), "log_level is validated")
+}
+
+// TestProviderValidate checks that a block setting only host and token
+// passes validation with the environment defaults of every other attribute,
+// and that the attributes are still validated when set
+func TestProviderValidate(t *testing.T) {
+	minimal := func() map[string]interface{} {
+		return map[string]interface{}{
+			"host":  "https://sonarqube.example.com",
+			"token": "squ_token",
+		}
+	}
+
+	diags := Provider().Validate(terraform.NewResourceConfigRaw(minimal()))
+	assert.False(t, diags.HasError(), "%v", diags)
+
+	for attr, value := range map[string]interface{}{
+		"metrics_textfile":  "metrics.txt",
+		"request_timeout":   "30",
+		"call_timeout":      "-2m",
+		"proxy_url":         "ftp://proxy.example.com",
+		"token_command_ttl": "forever",
+	} {
+		config := minimal()
+		config[attr] = value
+		assert.True(t, Provider().Validate(terraform.NewResourceConfigRaw(config)).HasError(), attr)
+	}
+}
+
+func TestRunShutdownHooksDoesNotWaitInLine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wrote := make(chan struct{})
+	err := runShutdownHooks(ctx, []func(context.Context) error{
+		func(ctx context.Context) error {
+			// A slow trace export, which only finishes once the next hook
+			// has written its file or the shutdown budget runs out
+			select {
+			case <-wrote:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("flushing traces: %w", ctx.Err())
+			}
+		},
+		func(context.Context) error {
+			close(wrote)
+			return nil
+		},
+	})
+
+	assert.NoError(t, err, "hooks run concurrently rather than in registration order")
+}
+
+func TestProviderConfigureDryRunReport(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+	diags := Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":           fake.URL,
+		"token":          fakesonar.DefaultToken,
+		"dry_run_report": filepath.Join(t.TempDir(), "dry-run.json"),
+	}))
+	require.True(t, diags.HasError())
+	assert.Equal(t, "dry_run_report requires dry_run", diags[0].Summary)
+
+	// The report itself is written by Shutdown, which ends the process
+	// lifecycle, so only dry_run is set here
+	diags = Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":    fake.URL,
+		"token":   fakesonar.DefaultToken,
+		"dry_run": true,
+	}))
+	assert.False(t, diags.HasError(), "%v", diags)
+}
+
+// newFakeSonar starts an in-memory SonarQube that is closed with the test
+func newFakeSonar(t *testing.T, opts ...fakesonar.Option) *fakesonar.Server {
+	t.Helper()
+	fake := fakesonar.NewServer(opts...)
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func testProviderFactories() map[string]func() (*schema.Provider, error) {
+	return map[string]func() (*schema.Provider, error){
+		"sonarqube": func() (*schema.Provider, error) {
+			return Provider(), nil
+		},
+	}
+}
+
+// testProviderConfig points the provider block at fake
+func testProviderConfig(fake *fakesonar.Server) string {
+	return fmt.Sprintf(`
+provider "sonarqube" {
+  host  = %q
+  token = %q
+}
+`, fake.URL, fakesonar.DefaultToken)
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
+)
+
+// resourceSonarqubeGroup manages a user group, identified by its name.
+// Renaming the group updates it in place, so its members and permissions
+// are kept.
+func resourceSonarqubeGroup() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: instrumented("sonarqube_group", "create", resourceGroupCreate),
+		ReadContext:   instrumented("sonarqube_group", "read", resourceGroupRead),
+		UpdateContext: instrumented("sonarqube_group", "update", resourceGroupUpdate),
+		DeleteContext: instrumented("sonarqube_group", "delete", resourceGroupDelete),
+
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+
+		Timeouts: resourceTimeouts(),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Group name. Changing it renames the group in place, keeping its members and permissions.",
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group, err := groups.CreateGroup(ctx, d.Get("name").(string), d.Get("description").(string))
+	if err != nil {
+		return apiError(ctx, err)
+	}
+
+	d.SetId(group.Name)
+	return resourceGroupRead(ctx, d, m)
+}
+
+func resourceGroupRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group, err := groups.GetGroup(ctx, d.Id())
+	if err != nil {
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube group %q not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
+	}
+
+	if err := d.Set("name", group.Name); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("description", group.Description); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	name := d.Get("name").(string)
+	if _, err := groups.UpdateGroup(ctx, d.Id(), name, d.Get("description").(string)); err != nil {
+		return apiError(ctx, err)
+	}
+
+	d.SetId(name)
+	return resourceGroupRead(ctx, d, m)
+}
+
+// resourceGroupDelete refuses to delete the default group, which every new
+// user joins and SonarQube cannot run without
+func resourceGroupDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group, err := groups.GetGroup(ctx, d.Id())
+	if client.IsNotFound(err) {
+		d.SetId("")
+		return nil
+	} else if err != nil {
+		return apiError(ctx, err)
+	}
+
+	if group.Default {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Cannot delete the default group %q", group.Name),
+			Detail:   "SonarQube adds every new user to the default group and cannot delete it. Run `terraform state rm` to stop managing it instead.",
+		}}
+	}
+
+	if err := groups.DeleteGroup(ctx, d.Id()); err != nil && !client.IsNotFound(err) {
+		return apiError(ctx, err)
+	}
+
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
+	"strings"
+)
+
+// resourceSonarqubeGroupMember adds one user to a group, leaving the other
+// members alone. Its ID is "<group>/<login>"; logins cannot contain a slash,
+// so the ID is split on the last one.
+func resourceSonarqubeGroupMember() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: instrumented("sonarqube_group_member", "create", resourceGroupMemberCreate),
+		ReadContext:   instrumented("sonarqube_group_member", "read", resourceGroupMemberRead),
+		DeleteContext: instrumented("sonarqube_group_member", "delete", resourceGroupMemberDelete),
+
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+
+		Timeouts: resourceTimeouts(),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the group",
+			},
+			"login": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Login of the user to add to the group",
+			},
+		},
+	}
+}
+
+func parseGroupMemberID(id string) (string, string, error) {
+	i := strings.LastIndex(id, "/")
+	if i <= 0 || i == len(id)-1 {
+		return "", "", fmt.Errorf("invalid group member ID %q, expected <group>/<login>", id)
+	}
+	return id[:i], id[i+1:], nil
+}
+
+// isGroupMember reports whether login is a member of group. It returns a
+// not-found error when the group does not exist.
+func isGroupMember(ctx context.Context, groups client.Groups, group, login string) (bool, error) {
+	it := groups.SearchGroupMembers(ctx, group, login)
+	for it.Next() {
+		if it.Item().Login == login {
+			return true, nil
+		}
+	}
+	return false, it.Err()
+}
+
+func resourceGroupMemberCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group := d.Get("name").(string)
+	login := d.Get("login").(string)
+	if err := groups.AddGroupMember(ctx, group, login); err != nil {
+		return apiError(ctx, err)
+	}
+
+	d.SetId(group + "/" + login)
+	return resourceGroupMemberRead(ctx, d, m)
+}
+
+func resourceGroupMemberRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group, login, err := parseGroupMemberID(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	member, err := isGroupMember(ctx, groups, group, login)
+	if err != nil && !client.IsNotFound(err) {
+		return apiError(ctx, err)
+	}
+	if !member {
+		log.Printf("[WARN] SonarQube user %q is not a member of group %q, removing from state", login, group)
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("name", group); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("login", login); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceGroupMemberDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	group, login, err := parseGroupMemberID(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := groups.RemoveGroupMember(ctx, group, login); err != nil && !client.IsNotFound(err) {
+		return apiError(ctx, err)
+	}
+
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestResourceGroupMember(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutUser(fakesonar.User{Login: "alice", Name: "Alice", Local: true, Active: true})
+	fake.PutUser(fakesonar.User{Login: "bob", Name: "Bob", Local: true, Active: true})
+
+	config := testProviderConfig(fake) + `
+resource "sonarqube_group" "test" {
+  name = "developers"
+}
+
+resource "sonarqube_group_member" "alice" {
+  name  = sonarqube_group.test.name
+  login = "alice"
+}
+`
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_group_member.alice", "id", "developers/alice"),
+					testCheckGroupMembers(fake, "developers", "alice"),
+				),
+			},
+			{
+				// Members added by hand are left alone
+				PreConfig: func() { fake.AddGroupMember("developers", "bob") },
+				Config:    config,
+				Check:     testCheckGroupMembers(fake, "developers", "alice", "bob"),
+			},
+			{
+				ResourceName:      "sonarqube_group_member.alice",
+				ImportState:       true,
+				ImportStateId:     "developers/alice",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testCheckGroupMembers(fake *fakesonar.Server, group string, logins ...string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		g := fake.Group(group)
+		if g == nil {
+			return fmt.Errorf("group %q does not exist in SonarQube", group)
+		}
+		want := map[string]bool{}
+		for _, login := range logins {
+			want[login] = true
+		}
+		if fmt.Sprint(g.Members) != fmt.Sprint(want) {
+			return fmt.Errorf("group %q has members %v, want %v", group, g.Members, want)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
+	"sort"
+)
+
+// resourceSonarqubeGroupMembers owns the whole member list of a group:
+// members added outside Terraform show up as removals in the next plan and
+// are removed on apply. The ID is the group name.
+func resourceSonarqubeGroupMembers() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: instrumented("sonarqube_group_members", "create", resourceGroupMembersCreate),
+		ReadContext:   instrumented("sonarqube_group_members", "read", resourceGroupMembersRead),
+		UpdateContext: instrumented("sonarqube_group_members", "update", resourceGroupMembersUpdate),
+		DeleteContext: instrumented("sonarqube_group_members", "delete", resourceGroupMembersDelete),
+
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+
+		Timeouts: resourceTimeouts(),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the group",
+			},
+			"logins": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Logins of every member of the group. Members not listed are removed; an empty list removes them all.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// groupMemberLogins lists the logins of every member of group, following
+// the pagination of api/user_groups/users
+func groupMemberLogins(ctx context.Context, groups client.Groups, group string) ([]string, error) {
+	members, err := groups.SearchGroupMembers(ctx, group, "").All()
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make([]string, 0, len(members))
+	for _, member := range members {
+		logins = append(logins, member.Login)
+	}
+	sort.Strings(logins)
+	return logins, nil
+}
+
+// setGroupMembers adds and removes members until the members of group are
+// exactly the configured logins
+func setGroupMembers(ctx context.Context, d *schema.ResourceData, groups client.Groups) diag.Diagnostics {
+	group := d.Get("name").(string)
+
+	current, err := groupMemberLogins(ctx, groups, group)
+	if err != nil {
+		return apiError(ctx, err)
+	}
+
+	want := d.Get("logins").(*schema.Set)
+	for _, login := range current {
+		if want.Contains(login) {
+			continue
+		}
+		if err := groups.RemoveGroupMember(ctx, group, login); err != nil {
+			return apiError(ctx, err)
+		}
+	}
+
+	has := map[string]bool{}
+	for _, login := range current {
+		has[login] = true
+	}
+	for _, login := range want.List() {
+		if has[login.(string)] {
+			continue
+		}
+		if err := groups.AddGroupMember(ctx, group, login.(string)); err != nil {
+			return apiError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+func resourceGroupMembersCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	name := d.Get("name").(string)
+	group, err := groups.GetGroup(ctx, name)
+	if err != nil {
+		return apiError(ctx, err)
+	}
+	if group.Default {
+		return diag.Errorf("cannot manage the members of the default group %q: SonarQube adds every user to it and refuses to remove them", name)
+	}
+
+	if diags := setGroupMembers(ctx, d, groups); diags.HasError() {
+		return diags
+	}
+
+	d.SetId(name)
+	return resourceGroupMembersRead(ctx, d, m)
+}
+
+func resourceGroupMembersRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	logins, err := groupMemberLogins(ctx, groups, d.Id())
+	if err != nil {
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube group %q not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
+	}
+
+	if err := d.Set("name", d.Id()); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("logins", logins); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceGroupMembersUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	if diags := setGroupMembers(ctx, d, groups); diags.HasError() {
+		return diags
+	}
+
+	return resourceGroupMembersRead(ctx, d, m)
+}
+
+// resourceGroupMembersDelete removes the members Terraform knows about,
+// leaving the group itself in place
+func resourceGroupMembersDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	groups := m.(client.Groups)
+
+	for _, login := range d.Get("logins").(*schema.Set).List() {
+		if err := groups.RemoveGroupMember(ctx, d.Id(), login.(string)); err != nil && !client.IsNotFound(err) {
+			return apiError(ctx, err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
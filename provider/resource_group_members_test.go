@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestResourceGroupMembers(t *testing.T) {
+	fake := newFakeSonar(t)
+	for _, login := range []string{"alice", "bob", "mallory"} {
+		fake.PutUser(fakesonar.User{Login: login, Name: login, Local: true, Active: true})
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testGroupMembersConfig(fake, `["alice", "bob"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_group_members.test", "id", "developers"),
+					resource.TestCheckResourceAttr("sonarqube_group_members.test", "logins.#", "2"),
+					testCheckGroupMembers(fake, "developers", "alice", "bob"),
+				),
+			},
+			{
+				// A member added by hand shows up in the plan
+				PreConfig:          func() { fake.AddGroupMember("developers", "mallory") },
+				Config:             testGroupMembersConfig(fake, `["alice", "bob"]`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testGroupMembersConfig(fake, `["bob"]`),
+				Check:  testCheckGroupMembers(fake, "developers", "bob"),
+			},
+			{
+				ResourceName:      "sonarqube_group_members.test",
+				ImportState:       true,
+				ImportStateId:     "developers",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestResourceGroupMembers_readsEveryPage(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  fake.URL,
+		"token": fakesonar.DefaultToken,
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+	groups := p.Meta().(client.Groups)
+
+	_, err := groups.CreateGroup(ctx, "developers", "")
+	require.NoError(t, err)
+	for i := 0; i < 510; i++ {
+		login := fmt.Sprintf("user-%03d", i)
+		fake.PutUser(fakesonar.User{Login: login, Name: login, Local: true, Active: true})
+		fake.AddGroupMember("developers", login)
+	}
+
+	d := resourceSonarqubeGroupMembers().TestResourceData()
+	d.SetId("developers")
+	require.False(t, resourceGroupMembersRead(ctx, d, p.Meta()).HasError())
+	assert.Equal(t, 510, d.Get("logins").(*schema.Set).Len())
+}
+
+func TestResourceGroupMembers_refusesDefaultGroup(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  fake.URL,
+		"token": fakesonar.DefaultToken,
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+
+	d := resourceSonarqubeGroupMembers().TestResourceData()
+	require.NoError(t, d.Set("name", "sonar-users"))
+	diags = resourceGroupMembersCreate(ctx, d, p.Meta())
+	require.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Summary, "default group")
+	assert.Equal(t, map[string]bool{"admin": true}, fake.Group("sonar-users").Members)
+}
+
+func testGroupMembersConfig(fake *fakesonar.Server, logins string) string {
+	return testProviderConfig(fake) + fmt.Sprintf(`
+resource "sonarqube_group" "test" {
+  name = "developers"
+}
+
+resource "sonarqube_group_members" "test" {
+  name   = sonarqube_group.test.name
+  logins = %s
+}
+`, logins)
+}
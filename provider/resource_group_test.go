@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestResourceGroup(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy:      testCheckGroupDeleted(fake, "engineers"),
+		Steps: []resource.TestStep{
+			{
+				Config: testGroupConfig(fake, "developers", "Developers"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_group.test", "id", "developers"),
+					testCheckFakeGroup(fake, "developers", "Developers"),
+				),
+			},
+			{
+				// Renaming the group updates it in place
+				Config: testGroupConfig(fake, "engineers", "Engineering"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_group.test", "id", "engineers"),
+					testCheckFakeGroup(fake, "engineers", "Engineering"),
+					testCheckGroupDeleted(fake, "developers"),
+				),
+			},
+			{
+				ResourceName:      "sonarqube_group.test",
+				ImportState:       true,
+				ImportStateId:     "engineers",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestResourceGroup_refusesToDeleteDefaultGroup(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  fake.URL,
+		"token": fakesonar.DefaultToken,
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+
+	d := resourceSonarqubeGroup().TestResourceData()
+	d.SetId("sonar-users")
+	diags = resourceGroupDelete(ctx, d, p.Meta())
+	require.True(t, diags.HasError())
+	assert.Equal(t, `Cannot delete the default group "sonar-users"`, diags[0].Summary)
+	assert.NotNil(t, fake.Group("sonar-users"))
+}
+
+func testGroupConfig(fake *fakesonar.Server, name, description string) string {
+	return testProviderConfig(fake) + fmt.Sprintf(`
+resource "sonarqube_group" "test" {
+  name        = %q
+  description = %q
+}
+`, name, description)
+}
+
+func testCheckFakeGroup(fake *fakesonar.Server, name, description string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		g := fake.Group(name)
+		if g == nil {
+			return fmt.Errorf("group %q does not exist in SonarQube", name)
+		}
+		if g.Description != description {
+			return fmt.Errorf("group %q has description %q, want %q", name, g.Description, description)
+		}
+		return nil
+	}
+}
+
+func testCheckGroupDeleted(fake *fakesonar.Server, name string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if fake.Group(name) != nil {
+			return fmt.Errorf("group %q still exists in SonarQube", name)
+		}
+		return nil
+	}
+}
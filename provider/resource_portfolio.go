@@ -5,14 +5,27 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
 )
 
 func resourceSonarqubePortfolio() *schema.Resource {
 	return &schema.Resource{
-		CreateContext: resourcePortfolioCreate,
-		ReadContext:   resourcePortfolioRead,
-		UpdateContext: resourcePortfolioUpdate,
-		DeleteContext: resourcePortfolioDelete,
+		CreateContext: instrumented("sonarqube_portfolio", "create", resourcePortfolioCreate),
+		ReadContext:   instrumented("sonarqube_portfolio", "read", resourcePortfolioRead),
+		UpdateContext: instrumented("sonarqube_portfolio", "update", resourcePortfolioUpdate),
+		DeleteContext: instrumented("sonarqube_portfolio", "delete", resourcePortfolioDelete),
+
+		Timeouts: resourceTimeouts(),
+
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceSonarqubePortfolioV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourcePortfolioStateUpgradeV0,
+			},
+		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -114,10 +127,14 @@ func resourceSonarqubePortfolio() *schema.Resource {
 							},
 						},
 						"custom_metrics": {
-							Type:     schema.TypeMap,
+							Type:     schema.TypeSet,
 							Optional: true,
 							Elem: &schema.Resource{
 								Schema: map[string]*schema.Schema{
+									"metric": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
 									"operator": {
 										Type:     schema.TypeString,
 										Required: true,
@@ -137,7 +154,7 @@ func resourceSonarqubePortfolio() *schema.Resource {
 }
 
 func resourcePortfolioCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	portfolios := m.(client.Portfolios)
 
 	portfolio := &client.Portfolio{
 		Key:         d.Get("key").(string),
@@ -172,20 +189,32 @@ func resourcePortfolioCreate(ctx context.Context, d *schema.ResourceData, m inte
 		}
 	}
 
-	if err := client.CreatePortfolio(portfolio); err != nil {
-		return diag.FromErr(err)
+	if err := portfolios.CreatePortfolio(ctx, portfolio); err != nil {
+		return apiError(ctx, err)
 	}
 
 	d.SetId(portfolio.Key)
+
+	// Wait for the portfolio to be computed, so resources depending on it
+	// see its projects and measures
+	if _, err := m.(client.ComputeEngine).WaitForComponentQueue(ctx, portfolio.Key); err != nil {
+		return apiError(ctx, err)
+	}
+
 	return resourcePortfolioRead(ctx, d, m)
 }
 
 func resourcePortfolioRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	portfolios := m.(client.Portfolios)
 
-	portfolio, err := client.GetPortfolio(d.Id())
+	portfolio, err := portfolios.GetPortfolio(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube portfolio %q not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
 	}
 
 	d.Set("name", portfolio.Name)
@@ -209,7 +238,7 @@ func resourcePortfolioRead(ctx context.Context, d *schema.ResourceData, m interf
 }
 
 func resourcePortfolioUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	portfolios := m.(client.Portfolios)
 
 	portfolio := &client.Portfolio{
 		Key:         d.Get("key").(string),
@@ -244,19 +273,23 @@ func resourcePortfolioUpdate(ctx context.Context, d *schema.ResourceData, m inte
 		}
 	}
 
-	if err := client.UpdatePortfolio(portfolio); err != nil {
-		return diag.FromErr(err)
+	if err := portfolios.UpdatePortfolio(ctx, portfolio); err != nil {
+		return apiError(ctx, err)
+	}
+
+	if _, err := m.(client.ComputeEngine).WaitForComponentQueue(ctx, portfolio.Key); err != nil {
+		return apiError(ctx, err)
 	}
 
 	return resourcePortfolioRead(ctx, d, m)
 }
 
 func resourcePortfolioDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
+	portfolios := m.(client.Portfolios)
 
-	err := client.DeletePortfolio(d.Id())
+	err := portfolios.DeletePortfolio(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId("")
@@ -290,17 +323,16 @@ func expandPortfolioFilters(data map[string]interface{}) client.PortfolioFilters
 		}
 	}
 
-	if v, ok := data["compliance"]; ok {
-		compliance := v.([]interface{})[0].(map[string]interface{})
-		filters.Compliance = expandPortfolioCompliance(compliance)
+	if v, ok := data["compliance"].([]interface{}); ok && len(v) > 0 && v[0] != nil {
+		filters.Compliance = expandPortfolioCompliance(v[0].(map[string]interface{}))
 	}
 
 	if v, ok := data["custom_metrics"]; ok {
-		metrics := v.(map[string]interface{})
+		metrics := v.(*schema.Set).List()
 		filters.Metrics = make(map[string]client.PortfolioMetric)
-		for metric, value := range metrics {
+		for _, value := range metrics {
 			metricValue := value.(map[string]interface{})
-			filters.Metrics[metric] = client.PortfolioMetric{
+			filters.Metrics[metricValue["metric"].(string)] = client.PortfolioMetric{
 				Operator: metricValue["operator"].(string),
 				Value:    metricValue["value"].(string),
 			}
@@ -349,12 +381,13 @@ func flattenPortfolioFilters(filters *client.PortfolioFilters) []interface{} {
 	}
 
 	if len(filters.Metrics) > 0 {
-		metrics := make(map[string]interface{})
+		metrics := make([]interface{}, 0, len(filters.Metrics))
 		for k, v := range filters.Metrics {
-			metrics[k] = map[string]interface{}{
+			metrics = append(metrics, map[string]interface{}{
+				"metric":   k,
 				"operator": v.Operator,
 				"value":    v.Value,
-			}
+			})
 		}
 		m["custom_metrics"] = metrics
 	}
@@ -363,7 +396,9 @@ func flattenPortfolioFilters(filters *client.PortfolioFilters) []interface{} {
 }
 
 func flattenPortfolioCompliance(compliance *client.PortfolioCompliance) []interface{} {
-	if compliance == nil {
+	// An unset compliance block reads back as all zero values
+	if compliance == nil || (compliance.MinQualityGateStatus == "" && compliance.MinCoverage == 0 &&
+		compliance.MaxDuplications == 0 && compliance.MaxIssues == 0 && len(compliance.RequiredRules) == 0) {
 		return []interface{}{}
 	}
 
@@ -385,3 +420,86 @@ func stringSliceToInterfaceSlice(s []string) []interface{} {
 	}
 	return i
 }
+
+// resourceSonarqubePortfolioV0 is the schema before custom_metrics became a
+// set of blocks, when it was a map keyed by metric
+func resourceSonarqubePortfolioV0() *schema.Resource {
+	stringSet := func() *schema.Schema {
+		return &schema.Schema{Type: schema.TypeSet, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}}
+	}
+
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name":            {Type: schema.TypeString, Required: true},
+			"key":             {Type: schema.TypeString, Required: true, ForceNew: true},
+			"description":     {Type: schema.TypeString, Optional: true},
+			"selection_mode":  {Type: schema.TypeString, Required: true},
+			"projects":        stringSet(),
+			"project_pattern": {Type: schema.TypeString, Optional: true},
+			"branch_pattern":  {Type: schema.TypeString, Optional: true},
+			"filters": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"languages":     stringSet(),
+						"tags":          stringSet(),
+						"quality_gates": stringSet(),
+						"compliance": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"min_quality_gate_status": {Type: schema.TypeString, Optional: true},
+									"min_coverage":            {Type: schema.TypeFloat, Optional: true},
+									"max_duplications":        {Type: schema.TypeFloat, Optional: true},
+									"max_issues":              {Type: schema.TypeInt, Optional: true},
+									"required_rules":          stringSet(),
+								},
+							},
+						},
+						// The SDK stores a map with a resource Elem as a
+						// map of strings
+						"custom_metrics": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// resourcePortfolioStateUpgradeV0 turns the custom_metrics map into one
+// block per metric. Values that aren't an operator/value object are carried
+// over as the value with an empty operator, for the next refresh to fill in.
+func resourcePortfolioStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	filters, _ := rawState["filters"].([]interface{})
+	for _, f := range filters {
+		filter, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		metrics, _ := filter["custom_metrics"].(map[string]interface{})
+		blocks := make([]interface{}, 0, len(metrics))
+		for metric, v := range metrics {
+			block := map[string]interface{}{"metric": metric, "operator": "", "value": ""}
+			switch v := v.(type) {
+			case map[string]interface{}:
+				block["operator"] = v["operator"]
+				block["value"] = v["value"]
+			case string:
+				block["value"] = v
+			}
+			blocks = append(blocks, block)
+		}
+		filter["custom_metrics"] = blocks
+	}
+
+	return rawState, nil
+}
@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"regexp"
+	"testing"
+)
+
+func TestResourcePortfolio(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutProject(fakesonar.Project{Key: "svc-a", Name: "Service A"})
+	fake.PutProject(fakesonar.Project{Key: "svc-b", Name: "Service B"})
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy: func(*terraform.State) error {
+			if fake.Portfolio("platform") != nil {
+				return fmt.Errorf("portfolio %q still exists in SonarQube", "platform")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key            = "platform"
+  name           = "Platform"
+  selection_mode = "MANUAL"
+  projects       = ["svc-a", "svc-b"]
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_portfolio.test", "id", "platform"),
+					resource.TestCheckResourceAttr("sonarqube_portfolio.test", "projects.#", "2"),
+					testCheckFakePortfolio(fake, "platform", "MANUAL"),
+				),
+			},
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key             = "platform"
+  name            = "Platform Services"
+  selection_mode  = "REGEXP"
+  project_pattern = "svc-.*"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_portfolio.test", "name", "Platform Services"),
+					resource.TestCheckResourceAttr("sonarqube_portfolio.test", "project_pattern", "svc-.*"),
+					testCheckFakePortfolio(fake, "platform", "REGEXP"),
+				),
+			},
+			{
+				PreConfig: func() { fake.RemovePortfolio("platform") },
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key             = "platform"
+  name            = "Platform Services"
+  selection_mode  = "REGEXP"
+  project_pattern = "svc-.*"
+}
+`,
+				Check: testCheckFakePortfolio(fake, "platform", "REGEXP"),
+			},
+		},
+	})
+}
+
+func TestResourcePortfolio_customMetrics(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key            = "security"
+  name           = "Security"
+  selection_mode = "FILTER"
+
+  filters {
+    tags = ["security-critical"]
+
+    custom_metrics {
+      metric   = "security_rating"
+      operator = "GREATER_THAN_OR_EQUALS"
+      value    = "A"
+    }
+
+    custom_metrics {
+      metric   = "coverage"
+      operator = "LESS_THAN"
+      value    = "80"
+    }
+  }
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_portfolio.test", "filters.0.custom_metrics.#", "2"),
+					resource.TestCheckTypeSetElemNestedAttrs("sonarqube_portfolio.test", "filters.0.custom_metrics.*", map[string]string{
+						"metric":   "coverage",
+						"operator": "LESS_THAN",
+						"value":    "80",
+					}),
+					func(*terraform.State) error {
+						if m := fake.Portfolio("security").Filters.Metrics["security_rating"]; m.Value != "A" {
+							return fmt.Errorf("security_rating filter in SonarQube is %+v", m)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestResourcePortfolio_recalculationFails(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutProject(fakesonar.Project{Key: "svc-a", Name: "Service A"})
+	fake.FailTasks("platform", "Portfolio has too many projects", "java.lang.IllegalStateException")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key            = "platform"
+  name           = "Platform"
+  selection_mode = "MANUAL"
+  projects       = ["svc-a"]
+}
+`,
+				ExpectError: regexp.MustCompile(`VIEW_REFRESH task .* failed: Portfolio has too many projects`),
+			},
+		},
+	})
+}
+
+// Portfolios only exist on Enterprise Edition and above
+func TestResourcePortfolio_communityEdition(t *testing.T) {
+	fake := newFakeSonar(t, fakesonar.WithEdition("community"))
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_portfolio" "test" {
+  key             = "platform"
+  name            = "Platform"
+  selection_mode  = "REGEXP"
+  project_pattern = "svc-.*"
+}
+`,
+				ExpectError: regexp.MustCompile(`requires SonarQube .* edition`),
+			},
+		},
+	})
+}
+
+func TestResourcePortfolioStateUpgradeV0(t *testing.T) {
+	v0 := map[string]interface{}{
+		"key":            "security",
+		"selection_mode": "FILTER",
+		"filters": []interface{}{
+			map[string]interface{}{
+				"tags": []interface{}{"security-critical"},
+				"custom_metrics": map[string]interface{}{
+					"security_rating": map[string]interface{}{"operator": "GREATER_THAN", "value": "A"},
+					"coverage":        "80",
+				},
+			},
+		},
+	}
+
+	v1, err := resourcePortfolioStateUpgradeV0(context.Background(), v0, nil)
+	require.NoError(t, err)
+
+	filter := v1["filters"].([]interface{})[0].(map[string]interface{})
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"metric": "security_rating", "operator": "GREATER_THAN", "value": "A"},
+		map[string]interface{}{"metric": "coverage", "operator": "", "value": "80"},
+	}, filter["custom_metrics"])
+	assert.Equal(t, []interface{}{"security-critical"}, filter["tags"], "other filters are untouched")
+
+	raw, err := json.Marshal(v1)
+	require.NoError(t, err)
+	_, err = ctyjson.Unmarshal(raw, resourceSonarqubePortfolio().CoreConfigSchema().ImpliedType())
+	assert.NoError(t, err, "upgraded state decodes against the current schema")
+}
+
+func testCheckFakePortfolio(fake *fakesonar.Server, key, mode string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		p := fake.Portfolio(key)
+		if p == nil {
+			return fmt.Errorf("portfolio %q does not exist in SonarQube", key)
+		}
+		if p.Selection.Mode != mode {
+			return fmt.Errorf("portfolio %q has selection mode %q, want %q", key, p.Selection.Mode, mode)
+		}
+		return nil
+	}
+}
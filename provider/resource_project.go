@@ -2,17 +2,20 @@ package provider
 
 import (
 	"context"
-	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
 )
 
 func resourceSonarqubeProject() *schema.Resource {
 	return &schema.Resource{
-		CreateContext: resourceProjectCreate,
-		ReadContext:   resourceProjectRead,
-		UpdateContext: resourceProjectUpdate,
-		DeleteContext: resourceProjectDelete,
+		CreateContext: instrumented("sonarqube_project", "create", resourceProjectCreate),
+		ReadContext:   instrumented("sonarqube_project", "read", resourceProjectRead),
+		UpdateContext: instrumented("sonarqube_project", "update", resourceProjectUpdate),
+		DeleteContext: instrumented("sonarqube_project", "delete", resourceProjectDelete),
+
+		Timeouts: resourceTimeouts(),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -46,8 +49,8 @@ func resourceSonarqubeProject() *schema.Resource {
 }
 
 func resourceProjectCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
+	projects := m.(client.Projects)
+
 	name := d.Get("name").(string)
 	key := d.Get("project_key").(string)
 	visibility := d.Get("visibility").(string)
@@ -59,9 +62,9 @@ func resourceProjectCreate(ctx context.Context, d *schema.ResourceData, m interf
 		tags[i] = v.(string)
 	}
 
-	project, err := client.CreateProject(name, key, visibility, mainBranch, tags)
+	project, err := projects.CreateProject(ctx, name, key, visibility, mainBranch, tags)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(project.Key)
@@ -69,11 +72,16 @@ func resourceProjectCreate(ctx context.Context, d *schema.ResourceData, m interf
 }
 
 func resourceProjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
-	project, err := client.ReadProject(d.Id())
+	projects := m.(client.Projects)
+
+	project, err := projects.ReadProject(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube project %q not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
 	}
 
 	if err := d.Set("name", project.Name); err != nil {
@@ -96,8 +104,8 @@ func resourceProjectRead(ctx context.Context, d *schema.ResourceData, m interfac
 }
 
 func resourceProjectUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
+	projects := m.(client.Projects)
+
 	if d.HasChanges("name", "visibility", "tags") {
 		name := d.Get("name").(string)
 		visibility := d.Get("visibility").(string)
@@ -108,9 +116,9 @@ func resourceProjectUpdate(ctx context.Context, d *schema.ResourceData, m interf
 			tags[i] = v.(string)
 		}
 
-		_, err := client.UpdateProject(d.Id(), name, visibility, tags)
+		_, err := projects.UpdateProject(ctx, d.Id(), name, visibility, tags)
 		if err != nil {
-			return diag.FromErr(err)
+			return apiError(ctx, err)
 		}
 	}
 
@@ -118,11 +126,11 @@ func resourceProjectUpdate(ctx context.Context, d *schema.ResourceData, m interf
 }
 
 func resourceProjectDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
-	err := client.DeleteProject(d.Id())
+	projects := m.(client.Projects)
+
+	err := projects.DeleteProject(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId("")
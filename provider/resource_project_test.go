@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/tomer1983/terraform-provider-sonarqube/audit"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestResourceProject(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy:      testCheckProjectDestroyed(fake, "my-project"),
+		Steps: []resource.TestStep{
+			{
+				Config: testProjectConfig(fake, "My Project", "private", `["backend"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_project.test", "id", "my-project"),
+					resource.TestCheckResourceAttr("sonarqube_project.test", "visibility", "private"),
+					resource.TestCheckResourceAttr("sonarqube_project.test", "tags.0", "backend"),
+					testCheckFakeProject(fake, "my-project", "My Project"),
+				),
+			},
+			{
+				Config: testProjectConfig(fake, "Renamed Project", "public", `["backend", "go"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_project.test", "name", "Renamed Project"),
+					resource.TestCheckResourceAttr("sonarqube_project.test", "visibility", "public"),
+					resource.TestCheckResourceAttr("sonarqube_project.test", "tags.#", "2"),
+					testCheckFakeProject(fake, "my-project", "Renamed Project"),
+				),
+			},
+			{
+				// Deleted in the UI: the provider drops it from state and recreates it
+				PreConfig: func() { fake.RemoveProject("my-project") },
+				Config:    testProjectConfig(fake, "Renamed Project", "public", `["backend", "go"]`),
+				Check:     testCheckFakeProject(fake, "my-project", "Renamed Project"),
+			},
+		},
+	})
+}
+
+// The audit records of a create are filed under the ID the project was
+// created with, so querying a project's history starts with its creation
+func TestResourceProject_auditLogRecordsCreateUnderID(t *testing.T) {
+	fake := newFakeSonar(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "sonarqube" {
+  host      = %q
+  token     = %q
+  audit_log = %q
+}
+
+resource "sonarqube_project" "test" {
+  name        = "My Project"
+  project_key = "my-project"
+  visibility  = "private"
+}
+`, fake.URL, fakesonar.DefaultToken, path),
+				Check: func(*terraform.State) error {
+					records, err := audit.Read(path, audit.Query{ResourceType: "sonarqube_project", ResourceID: "my-project"})
+					if err != nil {
+						return err
+					}
+					if len(records) == 0 || records[0].Endpoint != "projects/create" || records[0].Operation != "create" {
+						return fmt.Errorf("the project's audit history does not start with its creation: %+v", records)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestResourceProject_invalidVisibility(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config:      testProjectConfig(fake, "My Project", "internal", `[]`),
+				ExpectError: regexp.MustCompile(`Value of parameter 'visibility'`),
+			},
+		},
+	})
+}
+
+func testProjectConfig(fake *fakesonar.Server, name, visibility, tags string) string {
+	return testProviderConfig(fake) + fmt.Sprintf(`
+resource "sonarqube_project" "test" {
+  name        = %q
+  project_key = "my-project"
+  visibility  = %q
+  tags        = %s
+}
+`, name, visibility, tags)
+}
+
+func testCheckFakeProject(fake *fakesonar.Server, key, name string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		p := fake.Project(key)
+		if p == nil {
+			return fmt.Errorf("project %q does not exist in SonarQube", key)
+		}
+		if p.Name != name {
+			return fmt.Errorf("project %q has name %q, want %q", key, p.Name, name)
+		}
+		return nil
+	}
+}
+
+func testCheckProjectDestroyed(fake *fakesonar.Server, key string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if fake.Project(key) != nil {
+			return fmt.Errorf("project %q still exists in SonarQube", key)
+		}
+		return nil
+	}
+}
+
+// stubProjects serves ReadProject from a map. Calling any other method
+// panics through the nil embedded interface.
+type stubProjects struct {
+	client.Projects
+	projects map[string]*client.Project
+}
+
+func (s *stubProjects) ReadProject(ctx context.Context, key string) (*client.Project, error) {
+	if p, ok := s.projects[key]; ok {
+		return p, nil
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Method: http.MethodGet, Endpoint: "projects/search"}
+}
+
+func TestResourceProjectReadWithStub(t *testing.T) {
+	stub := &stubProjects{projects: map[string]*client.Project{
+		"stubbed": {Key: "stubbed", Name: "Stubbed", Visibility: "public", MainBranch: "main", Tags: []string{"a"}},
+	}}
+
+	d := resourceSonarqubeProject().TestResourceData()
+	d.SetId("stubbed")
+	if diags := resourceProjectRead(context.Background(), d, stub); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+	if got := d.Get("name"); got != "Stubbed" {
+		t.Errorf("name = %q, want %q", got, "Stubbed")
+	}
+
+	d.SetId("gone")
+	if diags := resourceProjectRead(context.Background(), d, stub); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+	if d.Id() != "" {
+		t.Errorf("missing project was not removed from state")
+	}
+}
@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"regexp"
+	"sort"
+)
+
+// projectsCleanupFilters are the attributes that select the projects to
+// remove
+var projectsCleanupFilters = []string{"query", "projects", "analyzed_before", "on_provisioned_only"}
+
+// resourceSonarqubeProjectsCleanup removes the projects matching a filter
+// with bulk deletes on every apply. The projects are looked up while
+// planning and listed in preview, so the plan shows what will be removed;
+// apply only removes projects that were in the plan and still match.
+func resourceSonarqubeProjectsCleanup() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: instrumented("sonarqube_projects_cleanup", "create", resourceProjectsCleanupCreate),
+		ReadContext:   instrumented("sonarqube_projects_cleanup", "read", resourceProjectsCleanupRead),
+		UpdateContext: instrumented("sonarqube_projects_cleanup", "update", resourceProjectsCleanupUpdate),
+		DeleteContext: instrumented("sonarqube_projects_cleanup", "delete", resourceProjectsCleanupDelete),
+
+		CustomizeDiff: resourceProjectsCleanupDiff,
+
+		Timeouts: resourceTimeouts(),
+
+		Schema: map[string]*schema.Schema{
+			"query": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Remove projects whose name or key contains this text",
+			},
+			"projects": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Only remove projects with these keys",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"analyzed_before": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Remove projects last analyzed before this date (YYYY-MM-DD)",
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`), "must be a date in YYYY-MM-DD format"),
+			},
+			"on_provisioned_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only remove provisioned projects that were never analyzed",
+			},
+			"preview": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Keys of the projects matching the filter when the plan was made, which apply removes",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"removed": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Keys of the projects removed by the most recent apply",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// projectsCleanupGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff
+type projectsCleanupGetter interface {
+	Get(key string) interface{}
+}
+
+func projectsCleanupFilter(d projectsCleanupGetter) client.ProjectFilter {
+	filter := client.ProjectFilter{
+		Query:             d.Get("query").(string),
+		AnalyzedBefore:    d.Get("analyzed_before").(string),
+		OnProvisionedOnly: d.Get("on_provisioned_only").(bool),
+	}
+	for _, key := range d.Get("projects").(*schema.Set).List() {
+		filter.Projects = append(filter.Projects, key.(string))
+	}
+	return filter
+}
+
+func matchingProjectKeys(ctx context.Context, projects client.Projects, filter client.ProjectFilter) ([]string, error) {
+	// A long list of keys is looked up in chunks, as SonarQube caps the
+	// values of the projects parameter
+	listed := filter.Projects
+	var keys []string
+	for start := 0; start == 0 || start < len(listed); start += client.ProjectKeysPerCall {
+		if len(listed) > 0 {
+			filter.Projects = listed[start:min(start+client.ProjectKeysPerCall, len(listed))]
+		}
+		matches, err := projects.SearchProjectsByFilter(ctx, filter).All()
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range matches {
+			keys = append(keys, project.Key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// resourceProjectsCleanupDiff looks up the projects the apply would remove.
+// Finding any plans an update, so the cleanup runs again whenever projects
+// match.
+func resourceProjectsCleanupDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for _, attr := range projectsCleanupFilters {
+		if !d.NewValueKnown(attr) {
+			if err := d.SetNewComputed("preview"); err != nil {
+				return err
+			}
+			return d.SetNewComputed("removed")
+		}
+	}
+
+	filter := projectsCleanupFilter(d)
+	if filter.IsEmpty() {
+		return fmt.Errorf("one of query, projects, analyzed_before or on_provisioned_only must be set, or every project would be removed")
+	}
+
+	keys, err := matchingProjectKeys(ctx, m.(client.Projects), filter)
+	if err != nil {
+		return fmt.Errorf("failed to look up projects to remove: %w", err)
+	}
+	if d.Id() != "" && len(keys) == 0 {
+		return nil
+	}
+
+	if err := d.SetNew("preview", keys); err != nil {
+		return err
+	}
+	return d.SetNewComputed("removed")
+}
+
+func resourceProjectsCleanupCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if diags := removePlannedProjects(ctx, d, m); diags.HasError() {
+		return diags
+	}
+
+	d.SetId(id.UniqueId())
+	return nil
+}
+
+// resourceProjectsCleanupRead has nothing to refresh: the cleanup is not an
+// object in SonarQube, and the projects to remove are found while planning
+func resourceProjectsCleanupRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceProjectsCleanupUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return removePlannedProjects(ctx, d, m)
+}
+
+// resourceProjectsCleanupDelete only forgets the cleanup; removed projects
+// are not restored
+func resourceProjectsCleanupDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	d.SetId("")
+	return nil
+}
+
+// removePlannedProjects bulk deletes the projects in preview that still
+// match the filter. preview is empty when the filter was unknown while
+// planning, so nothing is removed until the next apply.
+func removePlannedProjects(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	projects := m.(client.Projects)
+
+	var planned []string
+	for _, key := range d.Get("preview").([]interface{}) {
+		planned = append(planned, key.(string))
+	}
+	if len(planned) == 0 {
+		if err := d.Set("removed", []string{}); err != nil {
+			return diag.FromErr(err)
+		}
+		return nil
+	}
+
+	filter := projectsCleanupFilter(d)
+	filter.Projects = planned
+
+	keys, err := matchingProjectKeys(ctx, projects, filter)
+	if err != nil {
+		return apiError(ctx, err)
+	}
+	if len(keys) > 0 {
+		if err := projects.BulkDeleteProjects(ctx, filter); err != nil {
+			// Record what the chunks that went through removed
+			var partial *client.BulkDeleteError
+			if errors.As(err, &partial) {
+				_ = d.Set("removed", intersectKeys(keys, partial.Deleted))
+			}
+			return apiError(ctx, err)
+		}
+	}
+
+	if err := d.Set("removed", keys); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// intersectKeys returns the keys of a that are also in b, in the order of a
+func intersectKeys(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, key := range b {
+		in[key] = true
+	}
+	keys := []string{}
+	for _, key := range a {
+		if in[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestResourceProjectsCleanup(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutProject(fakesonar.Project{Key: "svc-old", Name: "Old Service", LastAnalysisDate: "2021-06-01"})
+	fake.PutProject(fakesonar.Project{Key: "svc-new", Name: "New Service", LastAnalysisDate: "2024-02-01"})
+	fake.PutProject(fakesonar.Project{Key: "svc-never", Name: "Never Analyzed"})
+
+	config := testProviderConfig(fake) + `
+resource "sonarqube_projects_cleanup" "stale" {
+  query           = "svc-"
+  analyzed_before = "2023-01-01"
+}
+`
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_projects_cleanup.stale", "preview.#", "1"),
+					resource.TestCheckResourceAttr("sonarqube_projects_cleanup.stale", "removed.0", "svc-old"),
+					testCheckFakeProjectRemoved(fake, "svc-old", true),
+					testCheckFakeProjectRemoved(fake, "svc-new", false),
+					testCheckFakeProjectRemoved(fake, "svc-never", false),
+				),
+			},
+			{
+				PreConfig: func() {
+					fake.PutProject(fakesonar.Project{Key: "svc-legacy", Name: "Legacy", LastAnalysisDate: "2019-03-01"})
+				},
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_projects_cleanup.stale", "removed.#", "1"),
+					resource.TestCheckResourceAttr("sonarqube_projects_cleanup.stale", "removed.0", "svc-legacy"),
+					testCheckFakeProjectRemoved(fake, "svc-legacy", true),
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func testCheckFakeProjectRemoved(fake *fakesonar.Server, key string, removed bool) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if exists := fake.Project(key) != nil; exists == removed {
+			return fmt.Errorf("project %q exists in SonarQube: %t, want %t", key, exists, !removed)
+		}
+		return nil
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
 )
 
 func resourceSonarqubeQualityGate() *schema.Resource {
 	return &schema.Resource{
-		CreateContext: resourceQualityGateCreate,
-		ReadContext:   resourceQualityGateRead,
-		UpdateContext: resourceQualityGateUpdate,
-		DeleteContext: resourceQualityGateDelete,
+		CreateContext: instrumented("sonarqube_qualitygate", "create", resourceQualityGateCreate),
+		ReadContext:   instrumented("sonarqube_qualitygate", "read", resourceQualityGateRead),
+		UpdateContext: instrumented("sonarqube_qualitygate", "update", resourceQualityGateUpdate),
+		DeleteContext: instrumented("sonarqube_qualitygate", "delete", resourceQualityGateDelete),
+
+		Timeouts: resourceTimeouts(),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -23,6 +27,10 @@ func resourceSonarqubeQualityGate() *schema.Resource {
 				Optional: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
 						"metric": {
 							Type:     schema.TypeString,
 							Required: true,
@@ -43,13 +51,13 @@ func resourceSonarqubeQualityGate() *schema.Resource {
 }
 
 func resourceQualityGateCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
+	gates := m.(client.QualityGates)
+
 	name := d.Get("name").(string)
-	
-	gate, err := client.CreateQualityGate(name)
+
+	gate, err := gates.CreateQualityGate(ctx, name)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId(gate.ID)
@@ -59,14 +67,15 @@ func resourceQualityGateCreate(ctx context.Context, d *schema.ResourceData, m in
 		conditions := v.([]interface{})
 		for _, c := range conditions {
 			condition := c.(map[string]interface{})
-			_, err := client.CreateQualityGateCondition(
+			_, err := gates.CreateQualityGateCondition(
+				ctx,
 				gate.ID,
 				condition["metric"].(string),
 				condition["op"].(string),
 				condition["error"].(string),
 			)
 			if err != nil {
-				return diag.FromErr(err)
+				return apiError(ctx, err)
 			}
 		}
 	}
@@ -75,11 +84,16 @@ func resourceQualityGateCreate(ctx context.Context, d *schema.ResourceData, m in
 }
 
 func resourceQualityGateRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
-	gate, err := client.ReadQualityGate(d.Id())
+	gates := m.(client.QualityGates)
+
+	gate, err := gates.ReadQualityGate(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube quality gate %q not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
 	}
 
 	if err := d.Set("name", gate.Name); err != nil {
@@ -89,6 +103,7 @@ func resourceQualityGateRead(ctx context.Context, d *schema.ResourceData, m inte
 	conditions := make([]map[string]interface{}, len(gate.Conditions))
 	for i, c := range gate.Conditions {
 		conditions[i] = map[string]interface{}{
+			"id":     c.ID,
 			"metric": c.Metric,
 			"op":     c.Op,
 			"error":  c.Error,
@@ -103,26 +118,29 @@ func resourceQualityGateRead(ctx context.Context, d *schema.ResourceData, m inte
 }
 
 func resourceQualityGateUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
+	gates := m.(client.QualityGates)
+
 	if d.HasChange("name") {
-		_, err := client.UpdateQualityGate(d.Id(), d.Get("name").(string))
+		gate, err := gates.UpdateQualityGate(ctx, d.Id(), d.Get("name").(string))
 		if err != nil {
-			return diag.FromErr(err)
+			return apiError(ctx, err)
 		}
+		// Gates are identified by name on newer servers, so a rename moves the ID
+		d.SetId(gate.ID)
 	}
 
 	// Handle conditions update by recreating them
 	if d.HasChange("conditions") {
-		// Delete existing conditions
+		// Delete existing conditions, by the ids Read stored. One already
+		// deleted in SonarQube is gone either way.
 		old, _ := d.GetChange("conditions")
 		oldConditions := old.([]interface{})
 		for _, c := range oldConditions {
 			condition := c.(map[string]interface{})
-			if condition["id"] != nil {
-				err := client.DeleteQualityGateCondition(condition["id"].(string))
-				if err != nil {
-					return diag.FromErr(err)
+			if id, _ := condition["id"].(string); id != "" {
+				err := gates.DeleteQualityGateCondition(ctx, id)
+				if err != nil && !client.IsNotFound(err) {
+					return apiError(ctx, err)
 				}
 			}
 		}
@@ -131,14 +149,15 @@ func resourceQualityGateUpdate(ctx context.Context, d *schema.ResourceData, m in
 		conditions := d.Get("conditions").([]interface{})
 		for _, c := range conditions {
 			condition := c.(map[string]interface{})
-			_, err := client.CreateQualityGateCondition(
+			_, err := gates.CreateQualityGateCondition(
+				ctx,
 				d.Id(),
 				condition["metric"].(string),
 				condition["op"].(string),
 				condition["error"].(string),
 			)
 			if err != nil {
-				return diag.FromErr(err)
+				return apiError(ctx, err)
 			}
 		}
 	}
@@ -147,11 +166,11 @@ func resourceQualityGateUpdate(ctx context.Context, d *schema.ResourceData, m in
 }
 
 func resourceQualityGateDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*client.Client)
-	
-	err := client.DeleteQualityGate(d.Id())
+	gates := m.(client.QualityGates)
+
+	err := gates.DeleteQualityGate(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return apiError(ctx, err)
 	}
 
 	d.SetId("")
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"testing"
+)
+
+func TestResourceQualityGate(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy:      testCheckQualityGateDestroyed(fake, "Renamed Gate"),
+		Steps: []resource.TestStep{
+			{
+				Config: testQualityGateConfig(fake, "My Gate", "80"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_qualitygate.test", "id", "My Gate"),
+					resource.TestCheckResourceAttr("sonarqube_qualitygate.test", "conditions.#", "1"),
+					resource.TestCheckResourceAttr("sonarqube_qualitygate.test", "conditions.0.error", "80"),
+					resource.TestCheckResourceAttrSet("sonarqube_qualitygate.test", "conditions.0.id"),
+					testCheckFakeQualityGate(fake, "My Gate", 1),
+				),
+			},
+			{
+				Config: testQualityGateConfig(fake, "Renamed Gate", "90"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_qualitygate.test", "id", "Renamed Gate"),
+					resource.TestCheckResourceAttr("sonarqube_qualitygate.test", "conditions.0.error", "90"),
+					testCheckFakeQualityGate(fake, "Renamed Gate", 1),
+				),
+			},
+			{
+				PreConfig: func() { fake.RemoveQualityGate("Renamed Gate") },
+				Config:    testQualityGateConfig(fake, "Renamed Gate", "90"),
+				Check:     testCheckFakeQualityGate(fake, "Renamed Gate", 1),
+			},
+		},
+	})
+}
+
+// Servers before 8.4 address gates by numeric id
+func TestResourceQualityGate_legacyServer(t *testing.T) {
+	fake := newFakeSonar(t, fakesonar.WithVersion("8.3.1.34397"))
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy:      testCheckQualityGateDestroyed(fake, "My Gate"),
+		Steps: []resource.TestStep{
+			{
+				Config: testQualityGateConfig(fake, "My Gate", "80"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrWith("sonarqube_qualitygate.test", "id", func(id string) error {
+						if gate := fake.QualityGate("My Gate"); gate == nil || gate.ID != id {
+							return fmt.Errorf("state id %q does not match the gate's numeric id", id)
+						}
+						return nil
+					}),
+					testCheckFakeQualityGate(fake, "My Gate", 1),
+				),
+			},
+		},
+	})
+}
+
+// Under dry_run, replacing a condition deletes the old one in the overlay,
+// so Read shows only the new one while SonarQube keeps the old
+func TestResourceQualityGate_dryRunReplacesCondition(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	live := client.NewClient(fake.URL, fakesonar.DefaultToken)
+	_, err := live.CreateQualityGate(ctx, "Gate")
+	require.NoError(t, err)
+	coverage, err := live.CreateQualityGateCondition(ctx, "Gate", "coverage", "LT", "80")
+	require.NoError(t, err)
+
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":    fake.URL,
+		"token":   fakesonar.DefaultToken,
+		"dry_run": true,
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+
+	r := p.ResourcesMap["sonarqube_qualitygate"]
+	state := &terraform.InstanceState{
+		ID: "Gate",
+		Attributes: map[string]string{
+			"id":                  "Gate",
+			"name":                "Gate",
+			"conditions.#":        "1",
+			"conditions.0.id":     coverage.ID,
+			"conditions.0.metric": "coverage",
+			"conditions.0.op":     "LT",
+			"conditions.0.error":  "80",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name": "Gate",
+		"conditions": []interface{}{
+			map[string]interface{}{"metric": "coverage", "op": "LT", "error": "90"},
+		},
+	})
+
+	diff, err := r.Diff(ctx, state, config, p.Meta())
+	require.NoError(t, err)
+	updated, diags := r.Apply(ctx, state, diff, p.Meta())
+	require.False(t, diags.HasError(), "%v", diags)
+
+	assert.Equal(t, "1", updated.Attributes["conditions.#"], "the old condition is gone on read")
+	assert.Equal(t, "90", updated.Attributes["conditions.0.error"])
+	assert.NotEqual(t, coverage.ID, updated.Attributes["conditions.0.id"])
+
+	gate := fake.QualityGate("Gate")
+	require.Len(t, gate.Conditions, 1)
+	assert.Equal(t, "80", gate.Conditions[0].Error, "nothing reached SonarQube")
+}
+
+func testQualityGateConfig(fake *fakesonar.Server, name, coverage string) string {
+	return testProviderConfig(fake) + fmt.Sprintf(`
+resource "sonarqube_qualitygate" "test" {
+  name = %q
+
+  conditions {
+    metric = "coverage"
+    op     = "LT"
+    error  = %q
+  }
+}
+`, name, coverage)
+}
+
+func testCheckFakeQualityGate(fake *fakesonar.Server, name string, conditions int) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		gate := fake.QualityGate(name)
+		if gate == nil {
+			return fmt.Errorf("quality gate %q does not exist in SonarQube", name)
+		}
+		if len(gate.Conditions) != conditions {
+			return fmt.Errorf("quality gate %q has %d conditions, want %d", name, len(gate.Conditions), conditions)
+		}
+		return nil
+	}
+}
+
+func testCheckQualityGateDestroyed(fake *fakesonar.Server, name string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if fake.QualityGate(name) != nil {
+			return fmt.Errorf("quality gate %q still exists in SonarQube", name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"log"
+	"sort"
+)
+
+// resourceSonarqubeUser manages a local or external user. SonarQube never
+// deletes users, so destroying the resource deactivates the login, and
+// creating a deactivated login again reactivates it.
+func resourceSonarqubeUser() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: instrumented("sonarqube_user", "create", resourceUserCreate),
+		ReadContext:   instrumented("sonarqube_user", "read", resourceUserRead),
+		UpdateContext: instrumented("sonarqube_user", "update", resourceUserUpdate),
+		DeleteContext: instrumented("sonarqube_user", "delete", resourceUserDelete),
+
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+
+		CustomizeDiff: resourceUserDiff,
+
+		Timeouts: resourceTimeouts(),
+
+		Schema: map[string]*schema.Schema{
+			"login_name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "User login. Changing it renames the user in place, keeping its groups, permissions and tokens.",
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"email": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Password of a local user. It is only sent when it changes and is never read back, so changes made outside Terraform are not detected.",
+			},
+			"local": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				ForceNew:    true,
+				Description: "Whether the user logs in with a SonarQube password, rather than through an external identity provider",
+			},
+			"scm_accounts": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "SCM logins and emails attributed to the user in analyses",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// resourceUserDiff rejects a local user without a password and an external
+// user with one while planning, instead of failing the apply
+func resourceUserDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("password") || !d.NewValueKnown("local") {
+		return nil
+	}
+
+	password := d.Get("password").(string)
+	if local := d.Get("local").(bool); !local && password != "" {
+		return fmt.Errorf("password cannot be set for an external user (local = false)")
+	} else if local && password == "" && d.Id() == "" {
+		return fmt.Errorf("password is required to create a local user")
+	}
+	return nil
+}
+
+func userScmAccounts(d *schema.ResourceData) []string {
+	accounts := []string{}
+	for _, account := range d.Get("scm_accounts").(*schema.Set).List() {
+		accounts = append(accounts, account.(string))
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+func resourceUserCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	users := m.(client.Users)
+
+	login := d.Get("login_name").(string)
+	if _, err := users.GetDeactivatedUser(ctx, login); err == nil {
+		log.Printf("[INFO] Reactivating deactivated SonarQube user %q", login)
+	} else if !client.IsNotFound(err) {
+		return apiError(ctx, err)
+	}
+
+	user := &client.User{
+		Login:       login,
+		Name:        d.Get("name").(string),
+		Email:       d.Get("email").(string),
+		Local:       d.Get("local").(bool),
+		ScmAccounts: userScmAccounts(d),
+	}
+	if _, err := users.CreateUser(ctx, user, d.Get("password").(string)); err != nil {
+		return apiError(ctx, err)
+	}
+
+	d.SetId(login)
+	return resourceUserRead(ctx, d, m)
+}
+
+func resourceUserRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	users := m.(client.Users)
+
+	user, err := users.GetUser(ctx, d.Id())
+	if err != nil {
+		if client.IsNotFound(err) {
+			log.Printf("[WARN] SonarQube user %q not found or deactivated, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return apiError(ctx, err)
+	}
+
+	if err := d.Set("login_name", user.Login); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("name", user.Name); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("email", user.Email); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("local", user.Local); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("scm_accounts", user.ScmAccounts); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	users := m.(client.Users)
+
+	if d.HasChange("login_name") {
+		newLogin := d.Get("login_name").(string)
+		if err := users.UpdateUserLogin(ctx, d.Id(), newLogin); err != nil {
+			return apiError(ctx, err)
+		}
+		d.SetId(newLogin)
+	}
+
+	if d.HasChanges("name", "email", "scm_accounts") {
+		_, err := users.UpdateUser(ctx, d.Id(), d.Get("name").(string), d.Get("email").(string), userScmAccounts(d))
+		if err != nil {
+			return apiError(ctx, err)
+		}
+	}
+
+	if password := d.Get("password").(string); d.HasChange("password") && password != "" {
+		if err := users.ChangeUserPassword(ctx, d.Id(), password); err != nil {
+			return apiError(ctx, err)
+		}
+	}
+
+	return resourceUserRead(ctx, d, m)
+}
+
+// resourceUserDelete deactivates the user, as SonarQube cannot delete users
+func resourceUserDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	users := m.(client.Users)
+
+	if err := users.DeactivateUser(ctx, d.Id()); err != nil && !client.IsNotFound(err) {
+		return apiError(ctx, err)
+	}
+
+	d.SetId("")
+	return nil
+}
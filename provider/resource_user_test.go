@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"regexp"
+	"testing"
+)
+
+func TestResourceUser(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		CheckDestroy:      testCheckUserDeactivated(fake, "jane"),
+		Steps: []resource.TestStep{
+			{
+				Config: testUserConfig(fake, "jdoe", "J. Doe", "first-pass", `["jdoe@example.com"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_user.test", "id", "jdoe"),
+					resource.TestCheckResourceAttr("sonarqube_user.test", "scm_accounts.#", "1"),
+					testCheckFakeUser(fake, "jdoe", "J. Doe", "first-pass"),
+				),
+			},
+			{
+				// Renaming the login and changing the password update the user in place
+				Config: testUserConfig(fake, "jane", "Jane Doe", "second-pass", `["jane", "jane@example.com"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("sonarqube_user.test", "id", "jane"),
+					resource.TestCheckResourceAttr("sonarqube_user.test", "scm_accounts.#", "2"),
+					testCheckFakeUser(fake, "jane", "Jane Doe", "second-pass"),
+				),
+			},
+			{
+				ResourceName:            "sonarqube_user.test",
+				ImportState:             true,
+				ImportStateId:           "jane",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+		},
+	})
+}
+
+func TestResourceUser_reactivatesDeactivatedLogin(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutUser(fakesonar.User{Login: "jdoe", Name: "Former Employee", Local: true})
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testUserConfig(fake, "jdoe", "J. Doe", "first-pass", `[]`),
+				Check:  testCheckFakeUser(fake, "jdoe", "J. Doe", "first-pass"),
+			},
+		},
+	})
+}
+
+func TestResourceUser_externalUserWithPassword(t *testing.T) {
+	fake := newFakeSonar(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: testProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testProviderConfig(fake) + `
+resource "sonarqube_user" "test" {
+  login_name = "sso-user"
+  name       = "SSO User"
+  local      = false
+  password   = "not-allowed"
+}
+`,
+				ExpectError: regexp.MustCompile(`password cannot be set for an external user`),
+			},
+		},
+	})
+}
+
+func testUserConfig(fake *fakesonar.Server, login, name, password, scmAccounts string) string {
+	return testProviderConfig(fake) + fmt.Sprintf(`
+resource "sonarqube_user" "test" {
+  login_name   = %q
+  name         = %q
+  email        = "jane@example.com"
+  password     = %q
+  scm_accounts = %s
+}
+`, login, name, password, scmAccounts)
+}
+
+func testCheckFakeUser(fake *fakesonar.Server, login, name, password string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		u := fake.User(login)
+		if u == nil || !u.Active {
+			return fmt.Errorf("user %q is not active in SonarQube", login)
+		}
+		if u.Name != name {
+			return fmt.Errorf("user %q has name %q, want %q", login, u.Name, name)
+		}
+		if u.Password != password {
+			return fmt.Errorf("user %q has password %q, want %q", login, u.Password, password)
+		}
+		return nil
+	}
+}
+
+func testCheckUserDeactivated(fake *fakesonar.Server, login string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if u := fake.User(login); u != nil && u.Active {
+			return fmt.Errorf("user %q is still active in SonarQube", login)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package fakesonar
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Task is a Compute Engine task in the shape returned by api/ce/task
+type Task struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ComponentKey    string `json:"componentKey,omitempty"`
+	Status          string `json:"status"`
+	ErrorMessage    string `json:"errorMessage,omitempty"`
+	ErrorStacktrace string `json:"errorStacktrace,omitempty"`
+}
+
+type taskFailure struct {
+	message, stacktrace string
+}
+
+func (s *Server) ceRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/ce/task":      s.ceTask,
+		"/api/ce/component": s.ceComponent,
+	}
+}
+
+// PutTask adds a task as if SonarQube had submitted it, returning its ID.
+// Tasks left PENDING or IN_PROGRESS stay queued until SetTaskStatus moves
+// them on.
+func (s *Server) PutTask(t Task) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.ID == "" {
+		t.ID = "AX" + s.newID()
+	}
+	s.tasks = append(s.tasks, &t)
+	return t.ID
+}
+
+// SetTaskStatus changes the status of a task added with PutTask
+func (s *Server) SetTaskStatus(id, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tasks {
+		if t.ID == id {
+			t.Status = status
+		}
+	}
+}
+
+// FailTasks makes every task submitted for component from now on fail with
+// message and stacktrace. An empty message makes them succeed again.
+func (s *Server) FailTasks(component, message, stacktrace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message == "" {
+		delete(s.taskFailures, component)
+		return
+	}
+	s.taskFailures[component] = taskFailure{message: message, stacktrace: stacktrace}
+}
+
+// submitTask records a task that the fake runs to completion at once
+func (s *Server) submitTask(taskType, component string) {
+	t := &Task{ID: "AX" + s.newID(), Type: taskType, ComponentKey: component, Status: "SUCCESS"}
+	if failure, ok := s.taskFailures[component]; ok {
+		t.Status = "FAILED"
+		t.ErrorMessage = failure.message
+		t.ErrorStacktrace = failure.stacktrace
+	}
+	s.tasks = append(s.tasks, t)
+}
+
+func (s *Server) ceTask(w http.ResponseWriter, r *http.Request) {
+	if !requireParams(w, r, "id") {
+		return
+	}
+
+	id := r.Form.Get("id")
+	for _, t := range s.tasks {
+		if t.ID != id {
+			continue
+		}
+		cp := *t
+		if !strings.Contains(r.Form.Get("additionalFields"), "stacktrace") {
+			cp.ErrorStacktrace = ""
+		}
+		writeJSON(w, map[string]interface{}{"task": cp})
+		return
+	}
+	writeError(w, http.StatusNotFound, "No activity found for task '%s'", id)
+}
+
+func (s *Server) ceComponent(w http.ResponseWriter, r *http.Request) {
+	if !requireParams(w, r, "component") {
+		return
+	}
+
+	component := r.Form.Get("component")
+	queue := []Task{}
+	var current *Task
+	for _, t := range s.tasks {
+		if t.ComponentKey != component {
+			continue
+		}
+		cp := *t
+		cp.ErrorStacktrace = ""
+		if t.Status == "PENDING" || t.Status == "IN_PROGRESS" {
+			queue = append(queue, cp)
+		} else {
+			current = &cp
+		}
+	}
+
+	body := map[string]interface{}{"queue": queue}
+	if current != nil {
+		body["current"] = current
+	}
+	writeJSON(w, body)
+}
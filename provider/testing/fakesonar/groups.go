@@ -0,0 +1,396 @@
+package fakesonar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Group is the fake's record of a user group
+type Group struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Default     bool            `json:"default"`
+	Members     map[string]bool `json:"-"`
+}
+
+func (s *Server) groupRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/user_groups/create":      s.groupCreate,
+		"/api/user_groups/update":      s.groupUpdate,
+		"/api/user_groups/delete":      s.groupDelete,
+		"/api/user_groups/search":      s.groupSearch,
+		"/api/user_groups/add_user":    s.groupAddUser,
+		"/api/user_groups/remove_user": s.groupRemoveUser,
+		"/api/user_groups/users":       s.groupUsers,
+	}
+}
+
+// Group returns a copy of the group with name, or nil
+func (s *Server) Group(name string) *Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.groups[name]
+	if !ok {
+		return nil
+	}
+	cp := *g
+	cp.Members = map[string]bool{}
+	for login := range g.Members {
+		cp.Members[login] = true
+	}
+	return &cp
+}
+
+// PutGroup creates or replaces a group out of band, as if done in the UI
+func (s *Server) PutGroup(g Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g.ID == "" {
+		g.ID = s.newID()
+	}
+	if g.Members == nil {
+		g.Members = map[string]bool{}
+	}
+	s.groups[g.Name] = &g
+}
+
+// AddGroupMember adds a member out of band, as if done in the UI
+func (s *Server) AddGroupMember(group, login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g, ok := s.groups[group]; ok {
+		g.Members[login] = true
+	}
+}
+
+// RemoveGroup deletes a group out of band
+func (s *Server) RemoveGroup(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups, name)
+}
+
+func (s *Server) groupView(g *Group) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           g.ID,
+		"name":         g.Name,
+		"description":  g.Description,
+		"membersCount": len(g.Members),
+		"default":      g.Default,
+	}
+}
+
+// findGroup resolves a group by id or by the given name parameter
+func (s *Server) findGroup(w http.ResponseWriter, r *http.Request, nameParam string) (*Group, bool) {
+	if id := r.Form.Get("id"); id != "" {
+		for _, g := range s.groups {
+			if g.ID == id {
+				return g, true
+			}
+		}
+		writeError(w, http.StatusNotFound, "No group with id '%s'", id)
+		return nil, false
+	}
+
+	if !requireParams(w, r, nameParam) {
+		return nil, false
+	}
+	g, ok := s.groups[r.Form.Get(nameParam)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No group with name '%s'", r.Form.Get(nameParam))
+		return nil, false
+	}
+	return g, true
+}
+
+func (s *Server) groupCreate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "name") {
+		return
+	}
+
+	name := r.Form.Get("name")
+	if _, ok := s.groups[name]; ok {
+		writeError(w, http.StatusBadRequest, "Group '%s' already exists", name)
+		return
+	}
+
+	g := &Group{
+		ID:          s.newID(),
+		Name:        name,
+		Description: r.Form.Get("description"),
+		Members:     map[string]bool{},
+	}
+	s.groups[name] = g
+	writeJSON(w, map[string]interface{}{"group": s.groupView(g)})
+}
+
+func (s *Server) groupUpdate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, ok := s.findGroup(w, r, "currentName")
+	if !ok {
+		return
+	}
+
+	if name := r.Form.Get("name"); name != "" && name != g.Name {
+		if g.Default {
+			writeError(w, http.StatusBadRequest, "Default group '%s' cannot be used to perform this action", g.Name)
+			return
+		}
+		if _, taken := s.groups[name]; taken {
+			writeError(w, http.StatusBadRequest, "Group '%s' already exists", name)
+			return
+		}
+		delete(s.groups, g.Name)
+		g.Name = name
+		s.groups[name] = g
+	}
+	if _, ok := r.Form["description"]; ok {
+		g.Description = r.Form.Get("description")
+	}
+
+	writeJSON(w, map[string]interface{}{"group": s.groupView(g)})
+}
+
+func (s *Server) groupDelete(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, ok := s.findGroup(w, r, "name")
+	if !ok {
+		return
+	}
+	if g.Default {
+		writeError(w, http.StatusBadRequest, "Default group '%s' cannot be used to perform this action", g.Name)
+		return
+	}
+	if g.Name == "sonar-administrators" {
+		writeError(w, http.StatusBadRequest, "The last system admin group cannot be deleted")
+		return
+	}
+
+	delete(s.groups, g.Name)
+	for grant := range s.permissions {
+		if grant.group == g.Name {
+			delete(s.permissions, grant)
+		}
+	}
+	writeNoContent(w)
+}
+
+func (s *Server) groupSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.Form.Get("q")
+
+	var names []string
+	for name := range s.groups {
+		if matchesQuery(q, name) {
+			names = append(names, name)
+		}
+	}
+
+	selected, paging := page(r, names)
+	groups := make([]map[string]interface{}, 0, len(selected))
+	for _, name := range selected {
+		groups = append(groups, s.groupView(s.groups[name]))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"paging": paging,
+		"groups": groups,
+	})
+}
+
+func (s *Server) groupMembership(w http.ResponseWriter, r *http.Request) (*Group, *User, bool) {
+	if !requirePost(w, r) {
+		return nil, nil, false
+	}
+	g, ok := s.findGroup(w, r, "name")
+	if !ok {
+		return nil, nil, false
+	}
+	u, ok := s.findActiveUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	return g, u, true
+}
+
+func (s *Server) groupAddUser(w http.ResponseWriter, r *http.Request) {
+	g, u, ok := s.groupMembership(w, r)
+	if !ok {
+		return
+	}
+	g.Members[u.Login] = true
+	writeNoContent(w)
+}
+
+func (s *Server) groupRemoveUser(w http.ResponseWriter, r *http.Request) {
+	g, u, ok := s.groupMembership(w, r)
+	if !ok {
+		return
+	}
+	if g.Default {
+		writeError(w, http.StatusBadRequest, "Default group '%s' cannot be used to perform this action", g.Name)
+		return
+	}
+	delete(g.Members, u.Login)
+	writeNoContent(w)
+}
+
+func (s *Server) groupUsers(w http.ResponseWriter, r *http.Request) {
+	g, ok := s.findGroup(w, r, "name")
+	if !ok {
+		return
+	}
+
+	selected := r.Form.Get("selected")
+	if selected == "" {
+		selected = "selected"
+	}
+	q := r.Form.Get("q")
+
+	var logins []string
+	for login, u := range s.users {
+		if !u.Active || !matchesQuery(q, u.Login, u.Name) {
+			continue
+		}
+		member := g.Members[login]
+		if (selected == "selected" && !member) || (selected == "deselected" && member) {
+			continue
+		}
+		logins = append(logins, login)
+	}
+
+	pageLogins, paging := page(r, logins)
+	users := make([]map[string]interface{}, 0, len(pageLogins))
+	for _, login := range pageLogins {
+		users = append(users, map[string]interface{}{
+			"login":    login,
+			"name":     s.users[login].Name,
+			"selected": g.Members[login],
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"paging": paging,
+		"users":  users,
+	})
+}
+
+// The v2 API, served from 10.5, addresses groups by id and takes JSON
+
+func (s *Server) groupV2Routes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/v2/authorizations/groups":  s.groupV2Create,
+		"/api/v2/authorizations/groups/": s.groupV2Item,
+	}
+}
+
+func (s *Server) groupV2View(g *Group) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          g.ID,
+		"name":        g.Name,
+		"description": g.Description,
+		"managed":     false,
+		"default":     g.Default,
+	}
+}
+
+func writeV2Error(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (s *Server) groupV2Create(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeV2Error(w, http.StatusBadRequest, "Value {} for field name was rejected. Error: must not be empty.")
+		return
+	}
+	if _, ok := s.groups[body.Name]; ok {
+		writeV2Error(w, http.StatusConflict, "Group '"+body.Name+"' already exists")
+		return
+	}
+
+	g := &Group{
+		ID:          s.newID(),
+		Name:        body.Name,
+		Description: body.Description,
+		Members:     map[string]bool{},
+	}
+	s.groups[g.Name] = g
+	writeJSON(w, s.groupV2View(g))
+}
+
+func (s *Server) groupV2Item(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v2/authorizations/groups/")
+	var g *Group
+	for _, candidate := range s.groups {
+		if candidate.ID == id {
+			g = candidate
+		}
+	}
+	if g == nil {
+		writeV2Error(w, http.StatusNotFound, "Group '"+id+"' not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.groupV2View(g))
+	case http.MethodPatch:
+		var patch map[string]*string
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeV2Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if name, ok := patch["name"]; ok && name != nil && *name != g.Name {
+			if g.Default {
+				writeV2Error(w, http.StatusBadRequest, "Default group '"+g.Name+"' cannot be used to perform this action")
+				return
+			}
+			if _, taken := s.groups[*name]; taken {
+				writeV2Error(w, http.StatusConflict, "Group '"+*name+"' already exists")
+				return
+			}
+			delete(s.groups, g.Name)
+			g.Name = *name
+			s.groups[g.Name] = g
+		}
+		if description, ok := patch["description"]; ok && description != nil {
+			g.Description = *description
+		}
+		writeJSON(w, s.groupV2View(g))
+	case http.MethodDelete:
+		if g.Default {
+			writeV2Error(w, http.StatusBadRequest, "Default group '"+g.Name+"' cannot be used to perform this action")
+			return
+		}
+		if g.Name == "sonar-administrators" {
+			writeV2Error(w, http.StatusBadRequest, "The last system admin group cannot be deleted")
+			return
+		}
+		delete(s.groups, g.Name)
+		for grant := range s.permissions {
+			if grant.group == g.Name {
+				delete(s.permissions, grant)
+			}
+		}
+		writeNoContent(w)
+	default:
+		writeV2Error(w, http.StatusMethodNotAllowed, "HTTP method "+r.Method+" is not supported by this URL")
+	}
+}
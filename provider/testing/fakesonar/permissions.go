@@ -0,0 +1,170 @@
+package fakesonar
+
+import (
+	"net/http"
+	"sort"
+)
+
+type permissionGrant struct {
+	project    string
+	user       string
+	group      string
+	permission string
+}
+
+var (
+	globalPermissions = map[string]bool{
+		"admin": true, "gateadmin": true, "profileadmin": true, "provisioning": true,
+		"scan": true, "applicationcreator": true, "portfoliocreator": true,
+	}
+	projectPermissions = map[string]bool{
+		"admin": true, "codeviewer": true, "issueadmin": true, "securityhotspotadmin": true,
+		"scan": true, "user": true,
+	}
+)
+
+func (s *Server) permissionRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/permissions/add_user":     s.permissionChange(true, false),
+		"/api/permissions/remove_user":  s.permissionChange(false, false),
+		"/api/permissions/add_group":    s.permissionChange(true, true),
+		"/api/permissions/remove_group": s.permissionChange(false, true),
+		"/api/permissions/users":        s.permissionList(false),
+		"/api/permissions/groups":       s.permissionList(true),
+	}
+}
+
+// HasUserPermission reports whether login holds permission, globally when
+// project is empty
+func (s *Server) HasUserPermission(project, login, permission string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.permissions[permissionGrant{project: project, user: login, permission: permission}]
+}
+
+// HasGroupPermission reports whether group holds permission, globally when
+// project is empty
+func (s *Server) HasGroupPermission(project, group, permission string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.permissions[permissionGrant{project: project, group: group, permission: permission}]
+}
+
+// resolveGrant validates the permission parameters shared by all
+// permissions endpoints
+func (s *Server) resolveGrant(w http.ResponseWriter, r *http.Request) (permissionGrant, bool) {
+	grant := permissionGrant{
+		project:    r.Form.Get("projectKey"),
+		permission: r.Form.Get("permission"),
+	}
+
+	if grant.project != "" {
+		if _, ok := s.projects[grant.project]; !ok {
+			writeError(w, http.StatusNotFound, "Project key '%s' not found", grant.project)
+			return grant, false
+		}
+		if grant.permission != "" && !projectPermissions[grant.permission] {
+			writeError(w, http.StatusBadRequest, "Value of parameter 'permission' (%s) must be one of: [admin, codeviewer, issueadmin, securityhotspotadmin, scan, user]", grant.permission)
+			return grant, false
+		}
+	} else if grant.permission != "" && !globalPermissions[grant.permission] {
+		writeError(w, http.StatusBadRequest, "Value of parameter 'permission' (%s) must be one of: [admin, gateadmin, profileadmin, provisioning, scan, applicationcreator, portfoliocreator]", grant.permission)
+		return grant, false
+	}
+
+	return grant, true
+}
+
+func (s *Server) permissionChange(add, forGroup bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) || !requireParams(w, r, "permission") {
+			return
+		}
+		grant, ok := s.resolveGrant(w, r)
+		if !ok {
+			return
+		}
+
+		if forGroup {
+			if !requireParams(w, r, "groupName") {
+				return
+			}
+			grant.group = r.Form.Get("groupName")
+			if _, ok := s.groups[grant.group]; !ok && grant.group != "anyone" {
+				writeError(w, http.StatusNotFound, "No group with name '%s'", grant.group)
+				return
+			}
+		} else {
+			u, ok := s.findActiveUser(w, r)
+			if !ok {
+				return
+			}
+			grant.user = u.Login
+		}
+
+		if add {
+			s.permissions[grant] = true
+		} else {
+			delete(s.permissions, grant)
+		}
+		writeNoContent(w)
+	}
+}
+
+func (s *Server) permissionList(forGroups bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := s.resolveGrant(w, r)
+		if !ok {
+			return
+		}
+
+		held := map[string][]string{}
+		for grant := range s.permissions {
+			if grant.project != filter.project {
+				continue
+			}
+			if filter.permission != "" && grant.permission != filter.permission {
+				continue
+			}
+			subject := grant.user
+			if forGroups {
+				subject = grant.group
+			}
+			if subject == "" {
+				continue
+			}
+			held[subject] = append(held[subject], grant.permission)
+		}
+
+		var subjects []string
+		for subject := range held {
+			subjects = append(subjects, subject)
+		}
+		selected, paging := page(r, subjects)
+
+		entries := make([]map[string]interface{}, 0, len(selected))
+		for _, subject := range selected {
+			perms := held[subject]
+			sort.Strings(perms)
+			entry := map[string]interface{}{"permissions": perms}
+			if forGroups {
+				entry["name"] = subject
+			} else {
+				entry["login"] = subject
+				if u, ok := s.users[subject]; ok {
+					entry["name"] = u.Name
+				}
+			}
+			entries = append(entries, entry)
+		}
+
+		field := "users"
+		if forGroups {
+			field = "groups"
+		}
+		writeJSON(w, map[string]interface{}{
+			"paging": paging,
+			field:    entries,
+		})
+	}
+}
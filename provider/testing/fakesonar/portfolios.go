@@ -0,0 +1,243 @@
+package fakesonar
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Portfolio is the fake's record of a portfolio, in the shape returned by
+// portfolios/show
+type Portfolio struct {
+	Key         string             `json:"key"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Selection   PortfolioSelection `json:"selection"`
+	Filters     PortfolioFilters   `json:"filters"`
+}
+
+// PortfolioSelection mirrors portfolios/configure_selection
+type PortfolioSelection struct {
+	Mode           string   `json:"mode"`
+	Projects       []string `json:"projects,omitempty"`
+	ProjectPattern string   `json:"projectPattern,omitempty"`
+	BranchPattern  string   `json:"branchPattern,omitempty"`
+}
+
+// PortfolioFilters mirrors portfolios/configure_filters
+type PortfolioFilters struct {
+	Languages    []string                   `json:"languages,omitempty"`
+	Tags         []string                   `json:"tags,omitempty"`
+	QualityGates []string                   `json:"qualityGates,omitempty"`
+	Compliance   map[string]interface{}     `json:"compliance,omitempty"`
+	Metrics      map[string]PortfolioMetric `json:"metrics,omitempty"`
+}
+
+// PortfolioMetric is a custom metric filter
+type PortfolioMetric struct {
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+var validSelectionModes = map[string]bool{"NONE": true, "MANUAL": true, "REGEXP": true, "FILTER": true}
+
+func (s *Server) portfolioRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/portfolios/create":              s.portfolioCreate,
+		"/api/portfolios/update":              s.portfolioUpdate,
+		"/api/portfolios/delete":              s.portfolioDelete,
+		"/api/portfolios/show":                s.portfolioShow,
+		"/api/portfolios/configure_selection": s.portfolioConfigureSelection,
+		"/api/portfolios/configure_filters":   s.portfolioConfigureFilters,
+	}
+}
+
+// Portfolio returns a copy of the portfolio with key, or nil
+func (s *Server) Portfolio(key string) *Portfolio {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.portfolios[key]
+	if !ok {
+		return nil
+	}
+	cp := *p
+	return &cp
+}
+
+// RemovePortfolio deletes a portfolio out of band
+func (s *Server) RemovePortfolio(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.portfolios, key)
+}
+
+func (s *Server) findPortfolio(w http.ResponseWriter, r *http.Request) (*Portfolio, bool) {
+	if !requireParams(w, r, "key") {
+		return nil, false
+	}
+	p, ok := s.portfolios[r.Form.Get("key")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Portfolio '%s' not found", r.Form.Get("key"))
+		return nil, false
+	}
+	return p, true
+}
+
+func (s *Server) portfolioCreate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "key", "name") {
+		return
+	}
+
+	key := r.Form.Get("key")
+	if _, ok := s.portfolios[key]; ok {
+		writeError(w, http.StatusBadRequest, "Could not create Portfolio with key: \"%s\". A similar key already exists: \"%s\"", key, key)
+		return
+	}
+	if _, ok := s.projects[key]; ok {
+		writeError(w, http.StatusBadRequest, "Could not create Portfolio with key: \"%s\". A project with this key already exists", key)
+		return
+	}
+
+	s.portfolios[key] = &Portfolio{
+		Key:         key,
+		Name:        r.Form.Get("name"),
+		Description: r.Form.Get("description"),
+		Selection:   PortfolioSelection{Mode: "NONE"},
+	}
+	s.submitTask("VIEW_REFRESH", key)
+	writeNoContent(w)
+}
+
+func (s *Server) portfolioUpdate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "name") {
+		return
+	}
+	p, ok := s.findPortfolio(w, r)
+	if !ok {
+		return
+	}
+	p.Name = r.Form.Get("name")
+	p.Description = r.Form.Get("description")
+	writeNoContent(w)
+}
+
+func (s *Server) portfolioDelete(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	p, ok := s.findPortfolio(w, r)
+	if !ok {
+		return
+	}
+	delete(s.portfolios, p.Key)
+	writeNoContent(w)
+}
+
+func (s *Server) portfolioShow(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPortfolio(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (s *Server) portfolioConfigureSelection(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "mode") {
+		return
+	}
+	p, ok := s.findPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	mode := r.Form.Get("mode")
+	if !validSelectionModes[mode] {
+		writeError(w, http.StatusBadRequest, "Value of parameter 'mode' (%s) must be one of: [NONE, MANUAL, REGEXP, FILTER]", mode)
+		return
+	}
+
+	selection := PortfolioSelection{Mode: mode}
+	switch mode {
+	case "MANUAL":
+		for _, key := range splitList(r, "projects") {
+			if _, ok := s.projects[key]; !ok {
+				writeError(w, http.StatusNotFound, "Project '%s' not found", key)
+				return
+			}
+			selection.Projects = append(selection.Projects, key)
+		}
+	case "REGEXP":
+		if !requireParams(w, r, "projectPattern") {
+			return
+		}
+		selection.ProjectPattern = r.Form.Get("projectPattern")
+		selection.BranchPattern = r.Form.Get("branchPattern")
+	}
+
+	p.Selection = selection
+	s.submitTask("VIEW_REFRESH", p.Key)
+	writeNoContent(w)
+}
+
+func (s *Server) portfolioConfigureFilters(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	p, ok := s.findPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	filters := PortfolioFilters{
+		Languages:    splitList(r, "languages"),
+		Tags:         splitList(r, "tags"),
+		QualityGates: splitList(r, "qualityGates"),
+	}
+
+	compliance := map[string]interface{}{}
+	if v := r.Form.Get("minQualityGateStatus"); v != "" {
+		compliance["minQualityGateStatus"] = v
+	}
+	for _, name := range []string{"minCoverage", "maxDuplications"} {
+		if v := r.Form.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Value of parameter '%s' (%s) must be a number", name, v)
+				return
+			}
+			compliance[name] = f
+		}
+	}
+	if v := r.Form.Get("maxIssues"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Value of parameter 'maxIssues' (%s) must be an integer", v)
+			return
+		}
+		compliance["maxIssues"] = n
+	}
+	if rules := splitList(r, "requiredRules"); len(rules) > 0 {
+		compliance["requiredRules"] = rules
+	}
+	if len(compliance) > 0 {
+		filters.Compliance = compliance
+	}
+
+	for name := range r.Form {
+		if !strings.HasPrefix(name, "metric_") || !strings.HasSuffix(name, "_operator") {
+			continue
+		}
+		metric := strings.TrimSuffix(strings.TrimPrefix(name, "metric_"), "_operator")
+		if filters.Metrics == nil {
+			filters.Metrics = map[string]PortfolioMetric{}
+		}
+		filters.Metrics[metric] = PortfolioMetric{
+			Operator: r.Form.Get(name),
+			Value:    r.Form.Get("metric_" + metric + "_value"),
+		}
+	}
+
+	p.Filters = filters
+	writeNoContent(w)
+}
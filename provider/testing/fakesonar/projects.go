@@ -0,0 +1,247 @@
+package fakesonar
+
+import (
+	"net/http"
+)
+
+// Project is the fake's record of a project
+type Project struct {
+	Key        string   `json:"key"`
+	Name       string   `json:"name"`
+	Qualifier  string   `json:"qualifier"`
+	Visibility string   `json:"visibility"`
+	MainBranch string   `json:"mainBranch,omitempty"`
+	Tags       []string `json:"tags"`
+
+	// LastAnalysisDate is a yyyy-MM-dd date, empty for provisioned projects
+	LastAnalysisDate string `json:"lastAnalysisDate,omitempty"`
+}
+
+func (s *Server) projectRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/projects/create":            s.projectCreate,
+		"/api/projects/search":            s.projectSearch,
+		"/api/projects/update":            s.projectUpdate,
+		"/api/projects/update_visibility": s.projectUpdateVisibility,
+		"/api/project_tags/set":           s.projectTagsSet,
+		"/api/projects/delete":            s.projectDelete,
+		"/api/projects/bulk_delete":       s.projectBulkDelete,
+	}
+}
+
+// Project returns a copy of the project with key, or nil
+func (s *Server) Project(key string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[key]
+	if !ok {
+		return nil
+	}
+	cp := *p
+	return &cp
+}
+
+// PutProject creates or replaces a project out of band, as if done in the UI
+func (s *Server) PutProject(p Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.Qualifier == "" {
+		p.Qualifier = "TRK"
+	}
+	if p.Visibility == "" {
+		p.Visibility = "public"
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	s.projects[p.Key] = &p
+}
+
+// RemoveProject deletes a project out of band
+func (s *Server) RemoveProject(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.projects, key)
+}
+
+func (s *Server) projectCreate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "name", "project") {
+		return
+	}
+
+	key := r.Form.Get("project")
+	if _, ok := s.projects[key]; ok {
+		writeError(w, http.StatusBadRequest, "Could not create Project with key: \"%s\". A similar key already exists: \"%s\"", key, key)
+		return
+	}
+
+	visibility := r.Form.Get("visibility")
+	if visibility == "" {
+		visibility = "public"
+	}
+	if visibility != "public" && visibility != "private" {
+		writeError(w, http.StatusBadRequest, "Value of parameter 'visibility' (%s) must be one of: [private, public]", visibility)
+		return
+	}
+
+	p := &Project{
+		Key:        key,
+		Name:       r.Form.Get("name"),
+		Qualifier:  "TRK",
+		Visibility: visibility,
+		MainBranch: r.Form.Get("mainBranch"),
+		Tags:       splitList(r, "tags"),
+	}
+	if p.MainBranch == "" {
+		p.MainBranch = "main"
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	s.projects[key] = p
+
+	writeJSON(w, map[string]interface{}{"project": p})
+}
+
+// maxListValues is how many values SonarQube accepts in a list parameter
+// such as projects
+const maxListValues = 1000
+
+// checkProjectList rejects a projects parameter listing more keys than
+// SonarQube accepts
+func checkProjectList(w http.ResponseWriter, r *http.Request) bool {
+	if n := len(splitList(r, "projects")); n > maxListValues {
+		writeError(w, http.StatusBadRequest, "'projects' can contains only %d values, got %d", maxListValues, n)
+		return false
+	}
+	return true
+}
+
+// selectProjects returns the keys of the projects matching the filters
+// shared by projects/search and projects/bulk_delete
+func (s *Server) selectProjects(r *http.Request) []string {
+	filter := map[string]bool{}
+	for _, k := range splitList(r, "projects") {
+		filter[k] = true
+	}
+	q := r.Form.Get("q")
+	analyzedBefore := r.Form.Get("analyzedBefore")
+	provisionedOnly := r.Form.Get("onProvisionedOnly") == "true"
+
+	var keys []string
+	for key, p := range s.projects {
+		switch {
+		case len(filter) > 0 && !filter[key]:
+		case !matchesQuery(q, p.Key, p.Name):
+		case provisionedOnly && p.LastAnalysisDate != "":
+		case analyzedBefore != "" && (p.LastAnalysisDate == "" || p.LastAnalysisDate >= analyzedBefore):
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *Server) projectSearch(w http.ResponseWriter, r *http.Request) {
+	if !checkProjectList(w, r) {
+		return
+	}
+	keys := s.selectProjects(r)
+
+	selected, paging := page(r, keys)
+	components := make([]*Project, 0, len(selected))
+	for _, key := range selected {
+		components = append(components, s.projects[key])
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"paging":     paging,
+		"components": components,
+	})
+}
+
+func (s *Server) findProject(w http.ResponseWriter, r *http.Request) (*Project, bool) {
+	if !requireParams(w, r, "project") {
+		return nil, false
+	}
+	p, ok := s.projects[r.Form.Get("project")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project '%s' not found", r.Form.Get("project"))
+		return nil, false
+	}
+	return p, true
+}
+
+func (s *Server) projectUpdate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	p, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	if name := r.Form.Get("name"); name != "" {
+		p.Name = name
+	}
+	if visibility := r.Form.Get("visibility"); visibility != "" {
+		p.Visibility = visibility
+	}
+	if _, ok := r.Form["tags"]; ok {
+		p.Tags = append([]string{}, splitList(r, "tags")...)
+	}
+
+	writeNoContent(w)
+}
+
+func (s *Server) projectUpdateVisibility(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "visibility") {
+		return
+	}
+	p, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+	p.Visibility = r.Form.Get("visibility")
+	writeNoContent(w)
+}
+
+func (s *Server) projectTagsSet(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	p, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+	p.Tags = append([]string{}, splitList(r, "tags")...)
+	writeNoContent(w)
+}
+
+func (s *Server) projectDelete(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	p, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+	delete(s.projects, p.Key)
+	writeNoContent(w)
+}
+
+func (s *Server) projectBulkDelete(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !checkProjectList(w, r) {
+		return
+	}
+	if r.Form.Get("projects") == "" && r.Form.Get("q") == "" && r.Form.Get("analyzedBefore") == "" {
+		writeError(w, http.StatusBadRequest, "At least one parameter among analyzedBefore, projects and q must be provided")
+		return
+	}
+	for _, key := range s.selectProjects(r) {
+		delete(s.projects, key)
+	}
+	writeNoContent(w)
+}
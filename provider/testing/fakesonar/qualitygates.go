@@ -0,0 +1,264 @@
+package fakesonar
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// QualityGate is the fake's record of a quality gate
+type QualityGate struct {
+	ID         string       `json:"id,omitempty"`
+	Name       string       `json:"name"`
+	IsBuiltIn  bool         `json:"isBuiltIn"`
+	IsDefault  bool         `json:"isDefault"`
+	Conditions []*Condition `json:"conditions"`
+}
+
+// Condition is a quality gate condition
+type Condition struct {
+	ID     string `json:"id"`
+	Metric string `json:"metric"`
+	Op     string `json:"op"`
+	Error  string `json:"error"`
+}
+
+var validConditionOps = map[string]bool{"LT": true, "GT": true}
+
+func (s *Server) qualityGateRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/qualitygates/create":           s.gateCreate,
+		"/api/qualitygates/show":             s.gateShow,
+		"/api/qualitygates/list":             s.gateList,
+		"/api/qualitygates/rename":           s.gateRename,
+		"/api/qualitygates/destroy":          s.gateDestroy,
+		"/api/qualitygates/create_condition": s.gateCreateCondition,
+		"/api/qualitygates/update_condition": s.gateUpdateCondition,
+		"/api/qualitygates/delete_condition": s.gateDeleteCondition,
+	}
+}
+
+// QualityGate returns a copy of the gate with name, or nil
+func (s *Server) QualityGate(name string) *QualityGate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.gates[name]
+	if !ok {
+		return nil
+	}
+	cp := *g
+	cp.Conditions = append([]*Condition(nil), g.Conditions...)
+	return &cp
+}
+
+// RemoveQualityGate deletes a gate out of band
+func (s *Server) RemoveQualityGate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.gates, name)
+}
+
+// idsRemoved reports whether the fake is emulating 10.x, where quality gates
+// can only be addressed by name
+func (s *Server) idsRemoved() bool {
+	major, _ := strconv.Atoi(strings.SplitN(s.version, ".", 2)[0])
+	return major >= 10
+}
+
+// findGate resolves a gate by the id or name parameter, following the
+// parameter names of the calling endpoint
+func (s *Server) findGate(w http.ResponseWriter, r *http.Request, idParam, nameParam string) (*QualityGate, bool) {
+	name := r.Form.Get(nameParam)
+	id := r.Form.Get(idParam)
+	if s.idsRemoved() {
+		id = ""
+	}
+
+	switch {
+	case name != "":
+		if g, ok := s.gates[name]; ok {
+			return g, true
+		}
+		writeError(w, http.StatusNotFound, "No quality gate has been found for name %s", name)
+	case id != "":
+		for _, g := range s.gates {
+			if g.ID == id {
+				return g, true
+			}
+		}
+		writeError(w, http.StatusNotFound, "No quality gate has been found for id %s", id)
+	default:
+		writeError(w, http.StatusBadRequest, "The '%s' parameter is missing", nameParam)
+	}
+	return nil, false
+}
+
+func (s *Server) gateView(g *QualityGate) map[string]interface{} {
+	view := map[string]interface{}{
+		"name":       g.Name,
+		"isBuiltIn":  g.IsBuiltIn,
+		"isDefault":  g.IsDefault,
+		"conditions": g.Conditions,
+	}
+	if !s.idsRemoved() {
+		view["id"] = g.ID
+	}
+	return view
+}
+
+func (s *Server) gateCreate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "name") {
+		return
+	}
+
+	name := r.Form.Get("name")
+	if _, ok := s.gates[name]; ok {
+		writeError(w, http.StatusBadRequest, "Name has already been taken")
+		return
+	}
+
+	g := &QualityGate{ID: s.newID(), Name: name, Conditions: []*Condition{}}
+	s.gates[name] = g
+	writeJSON(w, s.gateView(g))
+}
+
+func (s *Server) gateShow(w http.ResponseWriter, r *http.Request) {
+	g, ok := s.findGate(w, r, "id", "name")
+	if !ok {
+		return
+	}
+	writeJSON(w, s.gateView(g))
+}
+
+func (s *Server) gateList(w http.ResponseWriter, r *http.Request) {
+	var gates []map[string]interface{}
+	for _, g := range s.gates {
+		gates = append(gates, s.gateView(g))
+	}
+	writeJSON(w, map[string]interface{}{"qualitygates": gates})
+}
+
+func (s *Server) gateRename(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "name") {
+		return
+	}
+	g, ok := s.findGate(w, r, "id", "currentName")
+	if !ok {
+		return
+	}
+
+	name := r.Form.Get("name")
+	if existing, ok := s.gates[name]; ok && existing != g {
+		writeError(w, http.StatusBadRequest, "Name '%s' has already been taken", name)
+		return
+	}
+	if g.IsBuiltIn {
+		writeError(w, http.StatusBadRequest, "Operation forbidden for built-in Quality Gate '%s'", g.Name)
+		return
+	}
+
+	delete(s.gates, g.Name)
+	g.Name = name
+	s.gates[name] = g
+	writeNoContent(w)
+}
+
+func (s *Server) gateDestroy(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, ok := s.findGate(w, r, "id", "name")
+	if !ok {
+		return
+	}
+	if g.IsBuiltIn {
+		writeError(w, http.StatusBadRequest, "Operation forbidden for built-in Quality Gate '%s'", g.Name)
+		return
+	}
+	delete(s.gates, g.Name)
+	writeNoContent(w)
+}
+
+func (s *Server) readCondition(w http.ResponseWriter, r *http.Request, c *Condition) bool {
+	if !requireParams(w, r, "metric", "error") {
+		return false
+	}
+	op := r.Form.Get("op")
+	if op == "" {
+		op = "GT"
+	}
+	if !validConditionOps[op] {
+		writeError(w, http.StatusBadRequest, "Value of parameter 'op' (%s) must be one of: [LT, GT]", op)
+		return false
+	}
+	c.Metric = r.Form.Get("metric")
+	c.Op = op
+	c.Error = r.Form.Get("error")
+	return true
+}
+
+func (s *Server) gateCreateCondition(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, ok := s.findGate(w, r, "gateId", "gateName")
+	if !ok {
+		return
+	}
+
+	c := &Condition{ID: s.newID()}
+	if !s.readCondition(w, r, c) {
+		return
+	}
+	for _, existing := range g.Conditions {
+		if existing.Metric == c.Metric {
+			writeError(w, http.StatusBadRequest, "Condition on metric '%s' already exists.", c.Metric)
+			return
+		}
+	}
+
+	g.Conditions = append(g.Conditions, c)
+	writeJSON(w, c)
+}
+
+func (s *Server) findCondition(w http.ResponseWriter, r *http.Request) (*QualityGate, int, bool) {
+	if !requireParams(w, r, "id") {
+		return nil, 0, false
+	}
+	id := r.Form.Get("id")
+	for _, g := range s.gates {
+		for i, c := range g.Conditions {
+			if c.ID == id {
+				return g, i, true
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, "No quality gate condition with uuid '%s'", id)
+	return nil, 0, false
+}
+
+func (s *Server) gateUpdateCondition(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, i, ok := s.findCondition(w, r)
+	if !ok {
+		return
+	}
+	if s.readCondition(w, r, g.Conditions[i]) {
+		writeNoContent(w)
+	}
+}
+
+func (s *Server) gateDeleteCondition(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	g, i, ok := s.findCondition(w, r)
+	if !ok {
+		return
+	}
+	g.Conditions = append(g.Conditions[:i], g.Conditions[i+1:]...)
+	writeNoContent(w)
+}
@@ -0,0 +1,378 @@
+// Package fakesonar provides an in-memory SonarQube Web API for unit tests.
+//
+// It models projects, quality gates and conditions, portfolios, users,
+// groups, permissions, settings and Compute Engine tasks closely enough that provider resources
+// can be exercised with resource.UnitTest without a real SonarQube
+// container. Errors use SonarQube's {"errors":[{"msg":...}]} format and
+// status codes.
+package fakesonar
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultToken is the token accepted by a new Server
+	DefaultToken = "fake-admin-token"
+
+	// DefaultVersion is the version reported by api/server/version
+	DefaultVersion = "9.9.1.69595"
+)
+
+// Server is a running fake SonarQube instance
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	password string
+	forced   bool
+	version  string
+	edition  string
+	status   string
+	nextID   int
+
+	projects     map[string]*Project
+	gates        map[string]*QualityGate
+	portfolios   map[string]*Portfolio
+	users        map[string]*User
+	groups       map[string]*Group
+	permissions  map[permissionGrant]bool
+	settings     map[settingKey]*Setting
+	tasks        []*Task
+	taskFailures map[string]taskFailure
+	defaultGroup string
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithToken changes the accepted token
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithAdminPassword lets the admin user log in with basic auth and this
+// password
+func WithAdminPassword(password string) Option {
+	return func(s *Server) {
+		s.password = password
+	}
+}
+
+// WithForceAuthentication emulates sonar.forceAuthentication=true: every
+// endpoint needs credentials, and before 10.0 a bearer token is refused as
+// it is by a real server
+func WithForceAuthentication() Option {
+	return func(s *Server) {
+		s.forced = true
+	}
+}
+
+// WithVersion changes the reported server version
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithEdition changes the reported edition. Portfolio endpoints are only
+// served for enterprise and datacenter, as on a real server.
+func WithEdition(edition string) Option {
+	return func(s *Server) {
+		s.edition = edition
+	}
+}
+
+// NewServer starts a fake SonarQube server. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		token:        DefaultToken,
+		version:      DefaultVersion,
+		edition:      "enterprise",
+		status:       "UP",
+		projects:     map[string]*Project{},
+		gates:        map[string]*QualityGate{},
+		portfolios:   map[string]*Portfolio{},
+		users:        map[string]*User{},
+		groups:       map[string]*Group{},
+		permissions:  map[permissionGrant]bool{},
+		settings:     map[settingKey]*Setting{},
+		taskFailures: map[string]taskFailure{},
+		defaultGroup: "sonar-users",
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.users["admin"] = &User{Login: "admin", Name: "Administrator", Active: true, Local: true}
+	s.groups["sonar-users"] = &Group{ID: s.newID(), Name: "sonar-users", Description: "Every authenticated user automatically belongs to this group", Default: true}
+	s.groups["sonar-administrators"] = &Group{ID: s.newID(), Name: "sonar-administrators", Description: "System administrators", Members: map[string]bool{"admin": true}}
+	s.groups["sonar-users"].Members = map[string]bool{"admin": true}
+
+	mux := http.NewServeMux()
+	s.routes(mux)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+
+	return s
+}
+
+// SetStatus changes the status reported by api/system/status. Any status
+// other than UP makes every other endpoint answer 503.
+func (s *Server) SetStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *Server) routes(mux *http.ServeMux) {
+	handlers := map[string]http.HandlerFunc{
+		"/api/server/version":          s.serverVersion,
+		"/api/navigation/global":       s.navigationGlobal,
+		"/api/system/status":           s.systemStatus,
+		"/api/authentication/validate": s.authenticationValidate,
+	}
+	for path, h := range s.projectRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.qualityGateRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.portfolioRoutes() {
+		handlers[path] = s.requireEdition(h, "enterprise", "datacenter")
+	}
+	for path, h := range s.userRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.groupRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.groupV2Routes() {
+		handlers[path] = s.requireVersion(h, 10, 5)
+	}
+	for path, h := range s.permissionRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.settingRoutes() {
+		handlers[path] = h
+	}
+	for path, h := range s.ceRoutes() {
+		handlers[path] = h
+	}
+
+	for path, h := range handlers {
+		mux.HandleFunc(path, s.locked(h))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Unknown url : %s", r.URL.Path)
+	})
+}
+
+// authenticate accepts the token as a bearer token or as the basic auth
+// username, like SonarQube does, and the admin login when a password is set
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		token, password, status, forced := s.token, s.password, s.status, s.forced
+		s.mu.Unlock()
+
+		public := !forced && (r.URL.Path == "/api/server/version" || r.URL.Path == "/api/system/status" ||
+			r.URL.Path == "/api/navigation/global")
+
+		if status != "UP" && r.URL.Path != "/api/system/status" {
+			writeError(w, http.StatusServiceUnavailable, "SonarQube is %s", status)
+			return
+		}
+
+		if !public {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if forced && !s.atLeast(10, 0) && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				provided = ""
+			}
+			if user, pass, ok := r.BasicAuth(); ok {
+				provided = user
+				if pass != "" && password != "" && user == "admin" && pass == password {
+					provided = token
+				}
+			}
+			if provided != token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) locked(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(w, r)
+	}
+}
+
+func (s *Server) requireEdition(h http.HandlerFunc, editions ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, e := range editions {
+			if s.edition == e {
+				h(w, r)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Unknown url : %s", r.URL.Path)
+	}
+}
+
+// requireVersion serves h only when the fake emulates major.minor or newer
+func (s *Server) requireVersion(h http.HandlerFunc, major, minor int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.atLeast(major, minor) {
+			h(w, r)
+			return
+		}
+		writeError(w, http.StatusNotFound, "Unknown url : %s", r.URL.Path)
+	}
+}
+
+// atLeast reports whether the emulated version is major.minor or newer
+func (s *Server) atLeast(major, minor int) bool {
+	parts := strings.SplitN(s.version, ".", 3)
+	gotMajor, _ := strconv.Atoi(parts[0])
+	gotMinor := 0
+	if len(parts) > 1 {
+		gotMinor, _ = strconv.Atoi(parts[1])
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "HTTP method %s is not supported by this URL", r.Method)
+		return false
+	}
+	return true
+}
+
+func (s *Server) serverVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(s.version))
+}
+
+func (s *Server) navigationGlobal(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"edition": s.edition,
+		"version": s.version,
+	})
+}
+
+func (s *Server) systemStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"status":  s.status,
+		"version": s.version,
+	})
+}
+
+func (s *Server) authenticationValidate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]bool{"valid": true})
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"msg": fmt.Sprintf(format, args...)}},
+	})
+}
+
+// requireParams writes a 400 for the first missing parameter
+func requireParams(w http.ResponseWriter, r *http.Request, names ...string) bool {
+	for _, name := range names {
+		if r.Form.Get(name) == "" {
+			writeError(w, http.StatusBadRequest, "The '%s' parameter is missing", name)
+			return false
+		}
+	}
+	return true
+}
+
+// splitList reads a parameter that may be given comma-separated or repeated
+func splitList(r *http.Request, name string) []string {
+	var values []string
+	for _, raw := range r.Form[name] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// page applies SonarQube's p/ps paging to a sorted list of keys and returns
+// the selected keys and the paging block
+func page(r *http.Request, keys []string) ([]string, map[string]int) {
+	sort.Strings(keys)
+
+	p, _ := strconv.Atoi(r.Form.Get("p"))
+	if p < 1 {
+		p = 1
+	}
+	ps, _ := strconv.Atoi(r.Form.Get("ps"))
+	if ps < 1 {
+		ps = 100
+	}
+
+	start := (p - 1) * ps
+	if start > len(keys) {
+		start = len(keys)
+	}
+	end := start + ps
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	return keys[start:end], map[string]int{"pageIndex": p, "pageSize": ps, "total": len(keys)}
+}
+
+func matchesQuery(q string, fields ...string) bool {
+	if q == "" {
+		return true
+	}
+	q = strings.ToLower(q)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), q) {
+			return true
+		}
+	}
+	return false
+}
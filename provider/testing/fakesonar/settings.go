@@ -0,0 +1,116 @@
+package fakesonar
+
+import (
+	"net/http"
+	"sort"
+)
+
+type settingKey struct {
+	component string
+	key       string
+}
+
+// Setting is a stored setting value. Multi-value settings use Values.
+type Setting struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+func (s *Server) settingRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/settings/set":    s.settingSet,
+		"/api/settings/reset":  s.settingReset,
+		"/api/settings/values": s.settingValues,
+	}
+}
+
+// Setting returns a copy of the setting on component, or globally when
+// component is empty, or nil if it is not set
+func (s *Server) Setting(component, key string) *Setting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	setting, ok := s.settings[settingKey{component: component, key: key}]
+	if !ok {
+		return nil
+	}
+	cp := *setting
+	return &cp
+}
+
+func (s *Server) checkComponent(w http.ResponseWriter, r *http.Request) (string, bool) {
+	component := r.Form.Get("component")
+	if component == "" {
+		return "", true
+	}
+	if _, ok := s.projects[component]; !ok {
+		writeError(w, http.StatusNotFound, "Component key '%s' not found", component)
+		return "", false
+	}
+	return component, true
+}
+
+func (s *Server) settingSet(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "key") {
+		return
+	}
+	component, ok := s.checkComponent(w, r)
+	if !ok {
+		return
+	}
+
+	_, hasValue := r.Form["value"]
+	_, hasValues := r.Form["values"]
+	if hasValue == hasValues {
+		writeError(w, http.StatusBadRequest, "Either 'value', 'values' or 'fieldValues' must be provided")
+		return
+	}
+
+	setting := &Setting{Key: r.Form.Get("key")}
+	if hasValue {
+		setting.Value = r.Form.Get("value")
+	} else {
+		setting.Values = append([]string{}, r.Form["values"]...)
+	}
+	s.settings[settingKey{component: component, key: setting.Key}] = setting
+	writeNoContent(w)
+}
+
+func (s *Server) settingReset(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "keys") {
+		return
+	}
+	component, ok := s.checkComponent(w, r)
+	if !ok {
+		return
+	}
+	for _, key := range splitList(r, "keys") {
+		delete(s.settings, settingKey{component: component, key: key})
+	}
+	writeNoContent(w)
+}
+
+func (s *Server) settingValues(w http.ResponseWriter, r *http.Request) {
+	component, ok := s.checkComponent(w, r)
+	if !ok {
+		return
+	}
+
+	keys := splitList(r, "keys")
+	wanted := map[string]bool{}
+	for _, k := range keys {
+		wanted[k] = true
+	}
+
+	settings := []*Setting{}
+	for sk, setting := range s.settings {
+		if sk.component != component || (len(wanted) > 0 && !wanted[sk.key]) {
+			continue
+		}
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	writeJSON(w, map[string]interface{}{"settings": settings})
+}
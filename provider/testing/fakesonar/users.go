@@ -0,0 +1,274 @@
+package fakesonar
+
+import (
+	"net/http"
+	"sort"
+)
+
+// User is the fake's record of a user
+type User struct {
+	Login       string   `json:"login"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email,omitempty"`
+	Active      bool     `json:"active"`
+	Local       bool     `json:"local"`
+	ScmAccounts []string `json:"scmAccounts"`
+	Groups      []string `json:"groups,omitempty"`
+
+	// Password is recorded so tests can assert it was sent, but is never
+	// returned by the API
+	Password string `json:"-"`
+}
+
+func (s *Server) userRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/users/create":          s.userCreate,
+		"/api/users/update":          s.userUpdate,
+		"/api/users/update_login":    s.userUpdateLogin,
+		"/api/users/change_password": s.userChangePassword,
+		"/api/users/deactivate":      s.userDeactivate,
+		"/api/users/search":          s.userSearch,
+	}
+}
+
+// User returns a copy of the user with login, or nil
+func (s *Server) User(login string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[login]
+	if !ok {
+		return nil
+	}
+	cp := *u
+	cp.Groups = s.userGroups(login)
+	return &cp
+}
+
+// PutUser creates or replaces a user out of band
+func (s *Server) PutUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.ScmAccounts == nil {
+		u.ScmAccounts = []string{}
+	}
+	s.users[u.Login] = &u
+}
+
+// RemoveUser deletes a user out of band
+func (s *Server) RemoveUser(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, login)
+	for _, g := range s.groups {
+		delete(g.Members, login)
+	}
+}
+
+func (s *Server) userGroups(login string) []string {
+	var groups []string
+	for name, g := range s.groups {
+		if g.Members[login] {
+			groups = append(groups, name)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+func (s *Server) userView(u *User) *User {
+	cp := *u
+	cp.Groups = s.userGroups(u.Login)
+	return &cp
+}
+
+// scmAccounts reads the repeated scmAccount parameter, falling back to the
+// deprecated comma-separated scmAccounts. A single empty value clears the
+// accounts.
+func scmAccounts(r *http.Request) ([]string, bool) {
+	if values, ok := r.Form["scmAccount"]; ok {
+		accounts := []string{}
+		for _, v := range values {
+			if v != "" {
+				accounts = append(accounts, v)
+			}
+		}
+		return accounts, true
+	}
+	if _, ok := r.Form["scmAccounts"]; ok {
+		return append([]string{}, splitList(r, "scmAccounts")...), true
+	}
+	return nil, false
+}
+
+func (s *Server) findActiveUser(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	if !requireParams(w, r, "login") {
+		return nil, false
+	}
+	u, ok := s.users[r.Form.Get("login")]
+	if !ok || !u.Active {
+		writeError(w, http.StatusNotFound, "User '%s' doesn't exist", r.Form.Get("login"))
+		return nil, false
+	}
+	return u, true
+}
+
+func (s *Server) userCreate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "login", "name") {
+		return
+	}
+
+	login := r.Form.Get("login")
+	local := r.Form.Get("local") != "false"
+	password := r.Form.Get("password")
+	if local && password == "" {
+		writeError(w, http.StatusBadRequest, "Password is mandatory and must not be empty")
+		return
+	}
+	if !local && password != "" {
+		writeError(w, http.StatusBadRequest, "Password should only be set on local user")
+		return
+	}
+
+	existing, ok := s.users[login]
+	if ok && existing.Active {
+		writeError(w, http.StatusBadRequest, "An active user with login '%s' already exists", login)
+		return
+	}
+
+	accounts, _ := scmAccounts(r)
+	if accounts == nil {
+		accounts = []string{}
+	}
+
+	// SonarQube reactivates a previously deactivated login in place
+	u := &User{
+		Login:       login,
+		Name:        r.Form.Get("name"),
+		Email:       r.Form.Get("email"),
+		Active:      true,
+		Local:       local,
+		ScmAccounts: accounts,
+		Password:    password,
+	}
+	s.users[login] = u
+	if g, ok := s.groups[s.defaultGroup]; ok {
+		g.Members[login] = true
+	}
+
+	writeJSON(w, map[string]interface{}{"user": s.userView(u)})
+}
+
+func (s *Server) userUpdate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	u, ok := s.findActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, ok := r.Form["name"]; ok {
+		u.Name = r.Form.Get("name")
+	}
+	if _, ok := r.Form["email"]; ok {
+		u.Email = r.Form.Get("email")
+	}
+	if accounts, ok := scmAccounts(r); ok {
+		u.ScmAccounts = accounts
+	}
+
+	writeJSON(w, map[string]interface{}{"user": s.userView(u)})
+}
+
+func (s *Server) userUpdateLogin(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "newLogin") {
+		return
+	}
+	u, ok := s.findActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	newLogin := r.Form.Get("newLogin")
+	if _, taken := s.users[newLogin]; taken {
+		writeError(w, http.StatusBadRequest, "A user with login '%s' already exists", newLogin)
+		return
+	}
+
+	delete(s.users, u.Login)
+	for _, g := range s.groups {
+		if g.Members[u.Login] {
+			delete(g.Members, u.Login)
+			g.Members[newLogin] = true
+		}
+	}
+	u.Login = newLogin
+	s.users[newLogin] = u
+	writeNoContent(w)
+}
+
+func (s *Server) userChangePassword(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) || !requireParams(w, r, "password") {
+		return
+	}
+	u, ok := s.findActiveUser(w, r)
+	if !ok {
+		return
+	}
+	if !u.Local {
+		writeError(w, http.StatusBadRequest, "Password cannot be changed when external authentication is used")
+		return
+	}
+	u.Password = r.Form.Get("password")
+	writeNoContent(w)
+}
+
+func (s *Server) userDeactivate(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	u, ok := s.findActiveUser(w, r)
+	if !ok {
+		return
+	}
+	if u.Login == "admin" {
+		writeError(w, http.StatusBadRequest, "User is last administrator, and cannot be deactivated")
+		return
+	}
+
+	u.Active = false
+	u.ScmAccounts = []string{}
+	for _, g := range s.groups {
+		delete(g.Members, u.Login)
+	}
+	writeJSON(w, map[string]interface{}{"user": s.userView(u)})
+}
+
+func (s *Server) userSearch(w http.ResponseWriter, r *http.Request) {
+	deactivated := r.Form.Get("deactivated") == "true"
+	q := r.Form.Get("q")
+
+	var logins []string
+	for login, u := range s.users {
+		if u.Active == deactivated {
+			continue
+		}
+		if !matchesQuery(q, u.Login, u.Name, u.Email) {
+			continue
+		}
+		logins = append(logins, login)
+	}
+
+	selected, paging := page(r, logins)
+	users := make([]*User, 0, len(selected))
+	for _, login := range selected {
+		users = append(users, s.userView(s.users[login]))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"paging": paging,
+		"users":  users,
+	})
+}
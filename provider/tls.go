@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/tomer1983/terraform-provider-sonarqube/client"
+	"os"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// configureTLS builds the client's TLS configuration from the provider
+// block
+func configureTLS(d *schema.ResourceData) (*tls.Config, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	opts := client.TLSOptions{
+		MinVersion:         tlsVersions[d.Get("tls_min_version").(string)],
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+	}
+
+	// The pair is checked here rather than with RequiredWith, as their
+	// environment defaults always count as set
+	if (d.Get("client_cert").(string) == "") != (d.Get("client_key").(string) == "") {
+		return nil, diag.Errorf("client_cert and client_key must be set together")
+	}
+
+	for attr, dst := range map[string]*[]byte{
+		"ca_cert":     &opts.CABundle,
+		"client_cert": &opts.ClientCert,
+		"client_key":  &opts.ClientKey,
+	} {
+		data, err := pemOrFile(d.Get(attr).(string))
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Invalid %s", attr),
+				Detail:        err.Error(),
+				AttributePath: cty.GetAttrPath(attr),
+			}}
+		}
+		*dst = data
+	}
+
+	cfg, err := client.NewTLSConfig(opts)
+	if err != nil {
+		return nil, diag.Errorf("invalid TLS settings: %s", err)
+	}
+
+	if opts.InsecureSkipVerify {
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "SonarQube server certificate is not verified",
+			Detail:        "insecure_skip_verify is set, so the token is sent to whichever server answers at host. Trust the server's CA with ca_cert instead.",
+			AttributePath: cty.GetAttrPath("insecure_skip_verify"),
+		})
+	}
+	return cfg, diags
+}
+
+// pemOrFile returns value itself if it holds PEM data, and otherwise the
+// contents of the file it names
+func pemOrFile(value string) ([]byte, error) {
+	if value == "" || strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("not PEM data or a readable file: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPEM = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+func TestPEMOrFile(t *testing.T) {
+	data, err := pemOrFile(testPEM)
+	require.NoError(t, err)
+	assert.Equal(t, testPEM, string(data))
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte(testPEM), 0o600))
+	data, err = pemOrFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, testPEM, string(data))
+
+	_, err = pemOrFile(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestProviderConfigureTLS(t *testing.T) {
+	fake := newFakeSonar(t)
+	ctx := context.Background()
+
+	diags := Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":                 fake.URL,
+		"token":                fakesonar.DefaultToken,
+		"insecure_skip_verify": true,
+	}))
+	require.False(t, diags.HasError(), "%v", diags)
+	require.Len(t, diags, 1)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+
+	diags = Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":    fake.URL,
+		"token":   fakesonar.DefaultToken,
+		"ca_cert": "-----BEGIN CERTIFICATE-----\nnot base64\n-----END CERTIFICATE-----\n",
+	}))
+	require.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Summary, "CA bundle contains no PEM certificates")
+
+	diags = Provider().Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":        fake.URL,
+		"token":       fakesonar.DefaultToken,
+		"client_cert": "/etc/sonarqube/client.pem",
+	}))
+	require.True(t, diags.HasError())
+	assert.Equal(t, "client_cert and client_key must be set together", diags[0].Summary)
+}
@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	tracingProtocolHTTP = "http/protobuf"
+	tracingProtocolGRPC = "grpc"
+
+	defaultTracingServiceName = "terraform-provider-sonarqube"
+
+	// tracerName identifies spans created by the provider itself, as opposed
+	// to the client's per-request spans
+	tracerName = "terraform-provider-sonarqube"
+)
+
+// rootSpan covers the whole plugin process. Resource operation spans are
+// parented to it so one provider run is one trace.
+var rootSpan trace.Span
+
+func tracingSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Export OpenTelemetry traces of resource operations and SonarQube API requests over OTLP.",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"endpoint": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.IsURLWithScheme([]string{"http", "https"}),
+					Description:  "OTLP collector URL, e.g. `http://localhost:4318` for OTLP/HTTP or `http://localhost:4317` for gRPC. `http://` disables TLS.",
+				},
+				"protocol": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      tracingProtocolHTTP,
+					ValidateFunc: validation.StringInSlice([]string{tracingProtocolHTTP, tracingProtocolGRPC}, false),
+					Description:  "OTLP transport, `http/protobuf` or `grpc`.",
+				},
+				"headers": {
+					Type:        schema.TypeMap,
+					Optional:    true,
+					Sensitive:   true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "Headers sent with every export, e.g. collector API keys.",
+				},
+				"sampling_ratio": {
+					Type:         schema.TypeFloat,
+					Optional:     true,
+					Default:      1.0,
+					ValidateFunc: validation.FloatBetween(0, 1),
+					Description:  "Fraction of traces to sample. A sampled parent from `TRACEPARENT` is always honoured.",
+				},
+				"service_name": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     defaultTracingServiceName,
+					Description: "`service.name` resource attribute reported to the collector.",
+				},
+			},
+		},
+	}
+}
+
+// configureTracing installs a global tracer provider exporting to the
+// collector in the tracing block. It returns false when tracing is not
+// configured. Only the first configuration in a process takes effect, since
+// the tracer provider is process-wide.
+func configureTracing(ctx context.Context, d *schema.ResourceData) (bool, diag.Diagnostics) {
+	raw, ok := d.GetOk("tracing")
+	if !ok || len(raw.([]interface{})) == 0 || raw.([]interface{})[0] == nil {
+		return false, nil
+	}
+	config := raw.([]interface{})[0].(map[string]interface{})
+
+	lifecycleMu.Lock()
+	configured := rootSpan != nil
+	lifecycleMu.Unlock()
+	if configured {
+		return true, nil
+	}
+
+	headers := map[string]string{}
+	for k, v := range config["headers"].(map[string]interface{}) {
+		headers[k] = v.(string)
+	}
+
+	exporter, err := newTraceExporter(ctx, config["endpoint"].(string), config["protocol"].(string), headers)
+	if err != nil {
+		return false, diag.FromErr(err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(config["service_name"].(string)),
+	))
+	if err != nil {
+		return false, diag.FromErr(err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config["sampling_ratio"].(float64)))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// TRACEPARENT lets CI join the separate plan and apply plugin processes
+	// into the pipeline's trace
+	parent := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+	})
+	_, root := tp.Tracer(tracerName).Start(parent, "sonarqube.provider")
+
+	lifecycleMu.Lock()
+	rootSpan = root
+	lifecycleMu.Unlock()
+
+	onShutdown(func(ctx context.Context) error {
+		lifecycleMu.Lock()
+		rootSpan = nil
+		lifecycleMu.Unlock()
+
+		root.End()
+		if err := tp.Shutdown(ctx); err != nil {
+			return fmt.Errorf("flushing traces: %w", err)
+		}
+		return nil
+	})
+
+	return true, nil
+}
+
+func newTraceExporter(ctx context.Context, endpoint, protocol string, headers map[string]string) (*otlptrace.Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: %w", endpoint, err)
+	}
+	insecure := u.Scheme == "http"
+
+	if protocol == tracingProtocolGRPC {
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(u.Host),
+			otlptracegrpc.WithHeaders(headers),
+		}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithHeaders(headers),
+	}
+	if path := strings.TrimSuffix(u.Path, "/"); path != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(path+"/v1/traces"))
+	}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// startOperationSpan starts the span for a resource operation. Terraform's
+// contexts carry no span, so the process root span is used as the parent.
+func startOperationSpan(ctx context.Context, resourceName, operation string, d *schema.ResourceData) (context.Context, trace.Span) {
+	lifecycleMu.Lock()
+	root := rootSpan
+	lifecycleMu.Unlock()
+
+	if root != nil && !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.ContextWithSpan(ctx, root)
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, resourceName+"."+operation, trace.WithAttributes(
+		attribute.String("terraform.resource", resourceName),
+		attribute.String("terraform.operation", operation),
+		attribute.String("terraform.id", d.Id()),
+	))
+}
+
+// endOperationSpan records the outcome of a resource operation on span
+func endOperationSpan(span trace.Span, diags diag.Diagnostics) {
+	for _, d := range diags {
+		if d.Severity == diag.Error {
+			span.SetStatus(codes.Error, d.Summary)
+			break
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/tomer1983/terraform-provider-sonarqube/testing/fakesonar"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// otlpReceiver collects spans posted to an in-process OTLP/HTTP endpoint
+type otlpReceiver struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	spans map[string]*tracepb.Span
+}
+
+func newOTLPReceiver(t *testing.T) *otlpReceiver {
+	t.Helper()
+	r := &otlpReceiver{spans: map[string]*tracepb.Span{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/traces" {
+			http.NotFound(w, req)
+			return
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var export coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &export); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		r.mu.Lock()
+		for _, rs := range export.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					r.spans[span.Name] = span
+				}
+			}
+		}
+		r.mu.Unlock()
+
+		out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *otlpReceiver) span(name string) *tracepb.Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spans[name]
+}
+
+func TestTracingExportsOperationSpans(t *testing.T) {
+	fake := newFakeSonar(t)
+	fake.PutProject(fakesonar.Project{Key: "traced", Name: "Traced"})
+	receiver := newOTLPReceiver(t)
+
+	ctx := context.Background()
+	p := Provider()
+	diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  fake.URL,
+		"token": fakesonar.DefaultToken,
+		"tracing": []interface{}{map[string]interface{}{
+			"endpoint":     receiver.URL,
+			"service_name": "tracing-test",
+		}},
+	}))
+	if diags.HasError() {
+		t.Fatalf("configure: %v", diags)
+	}
+
+	project := p.ResourcesMap["sonarqube_project"]
+	d := project.TestResourceData()
+	d.SetId("traced")
+	if diags := project.ReadContext(ctx, d, p.Meta()); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	root := receiver.span("sonarqube.provider")
+	if root == nil {
+		t.Fatal("root span was not exported")
+	}
+	read := receiver.span("sonarqube_project.read")
+	if read == nil {
+		t.Fatal("operation span was not exported")
+	}
+	if string(read.TraceId) != string(root.TraceId) || string(read.ParentSpanId) != string(root.SpanId) {
+		t.Errorf("operation span is not a child of the provider span")
+	}
+}
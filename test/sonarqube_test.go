@@ -1,6 +1,8 @@
 package test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +19,22 @@ func TestSonarqubeModule(t *testing.T) {
 		},
 	}
 
+	// SONARQUBE_CASSETTE_MODE=record captures the run against a live server,
+	// SONARQUBE_CASSETTE_MODE=replay reruns it offline from the cassette
+	if mode := os.Getenv("SONARQUBE_CASSETTE_MODE"); mode != "" {
+		cassette, err := filepath.Abs("testdata/sonarqube_module.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode == "record" {
+			os.Remove(cassette)
+		}
+		terraformOptions.EnvVars = map[string]string{
+			"SONARQUBE_CASSETTE":      cassette,
+			"SONARQUBE_CASSETTE_MODE": mode,
+		}
+	}
+
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
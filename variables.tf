@@ -40,6 +40,7 @@ variable "users" {
     name     = string
     email    = string
     password = optional(string)
+    local    = optional(bool, true)
     scm_accounts = optional(list(string), [])
   }))
   default     = {}